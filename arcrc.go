@@ -0,0 +1,254 @@
+package phabricator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+)
+
+// Arcanist keeps its configuration in several places. In order of precedence:
+//   - .arcconfig in the working copy (project config)
+//   - ~/.arcrc (user config, also holds the credentials)
+//   - /etc/arcconfig (system config)
+//
+// The environment variables below override where the user and system
+// configs are read from.
+const (
+	// Path of the user config, instead of ~/.arcrc
+	ArcUserConfigEnv = "ARC_CONFIG"
+	// Path of the system config, instead of /etc/arcconfig
+	ArcSystemConfigEnv = "ARC_SYSTEM_CONFIG"
+
+	defaultSystemConfig = "/etc/arcconfig"
+	projectConfigName   = ".arcconfig"
+)
+
+type arcrcHost struct {
+	Token string `json:"token,omitempty"`
+	// Legacy certificate authentication, used before API tokens existed.
+	// See `arc install-certificate` in old Arcanist versions.
+	User string `json:"user,omitempty"`
+	Cert string `json:"cert,omitempty"`
+}
+
+type arcrcConfig struct {
+	Hosts  map[string]arcrcHost `json:"hosts"`
+	Config struct {
+		Default string `json:"default"`
+	} `json:"config"`
+}
+
+// arcUserConfigPath returns the path of .arcrc
+func arcUserConfigPath() (string, error) {
+	if p := os.Getenv(ArcUserConfigEnv); p != "" {
+		return p, nil
+	}
+	whoami, err := user.Current()
+	if err != nil {
+		msg := "Unable to determine current user"
+		logger.Error(msg)
+		return "", errors.New(msg)
+	}
+	return filepath.Join(whoami.HomeDir, ".arcrc"), nil
+}
+
+func arcSystemConfigPath() string {
+	if p := os.Getenv(ArcSystemConfigEnv); p != "" {
+		return p
+	}
+	return defaultSystemConfig
+}
+
+func arcConfig(arcrc io.Reader) (*arcrcConfig, error) {
+	if arcrc == nil {
+		arcrcPath, err := arcUserConfigPath()
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Open(arcrcPath)
+		if err != nil {
+			msg := "Unable to open ~/.arcrc"
+			logger.WithField("path", arcrcPath).Error(msg)
+			return nil, errors.New(msg)
+		}
+		defer f.Close()
+		arcrc = f
+	}
+
+	var arcCfg arcrcConfig
+	err := json.NewDecoder(arcrc).Decode(&arcCfg)
+	if err != nil {
+		logger.WithError(err).Error("Unable to parse .arcrc")
+		return nil, err
+	}
+	return &arcCfg, nil
+}
+
+// readFlatConfig reads the config format shared by .arcconfig and
+// /etc/arcconfig - a single JSON object of config keys.
+// A missing file is not an error.
+func readFlatConfig(configPath string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		logger.WithFields(log.Fields{
+			"error": err,
+			"path":  configPath,
+		}).Error("Unable to parse arc config")
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// findProjectConfig looks for .arcconfig in DIR and all its parents,
+// just like arc does when run inside a working copy
+func findProjectConfig(dir string) (map[string]interface{}, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		cfg, err := readFlatConfig(filepath.Join(dir, projectConfigName))
+		if err != nil || cfg != nil {
+			return cfg, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func configString(cfg map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := cfg[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// defaultHostURI finds the Phabricator host arc would talk to
+// when run from the current directory
+func defaultHostURI(arcCfg *arcrcConfig) string {
+	if wd, err := os.Getwd(); err == nil {
+		project, err := findProjectConfig(wd)
+		if err != nil {
+			logger.WithError(err).Warn("Ignoring unreadable .arcconfig")
+		}
+		// conduit_uri is what .arcconfig used before phabricator.uri
+		if uri := configString(project, "phabricator.uri", "conduit_uri"); uri != "" {
+			return uri
+		}
+	}
+	if arcCfg.Config.Default != "" {
+		return arcCfg.Config.Default
+	}
+	system, err := readFlatConfig(arcSystemConfigPath())
+	if err != nil {
+		logger.WithError(err).Warn("Ignoring unreadable system arc config")
+	}
+	return configString(system, "default")
+}
+
+// apiURI turns a Phabricator host URI into the API root
+// used as the key of .arcrc hosts
+func apiURI(hostURI string) (string, error) {
+	url, err := url.Parse(hostURI)
+	if err != nil {
+		msg := "Unable to parse default Phabricator URI"
+		logger.WithFields(log.Fields{
+			"error": err,
+			"url":   hostURI,
+		}).Error(msg)
+		return "", fmt.Errorf("%s: %s", msg, hostURI)
+	}
+	apiPath, _ := url.Parse("/api/")
+	return url.ResolveReference(apiPath).String(), nil
+}
+
+// WriteArcrcToken stores TOKEN for the API root API in the .arcrc file
+// at ARCRCPATH, the same way `arc install-certificate` does.
+// If ARCRCPATH is empty, ~/.arcrc (or $ARC_CONFIG) is used.
+// All other content of the file is preserved and the file is
+// always left readable by its owner only.
+func WriteArcrcToken(arcrcPath, api, token string) error {
+	if arcrcPath == "" {
+		var err error
+		if arcrcPath, err = arcUserConfigPath(); err != nil {
+			return err
+		}
+	}
+	logger := logger.WithFields(log.Fields{
+		"path": arcrcPath,
+		"api":  api,
+	})
+
+	// Decode loosely, so that we don't drop anything we don't know about
+	cfg := make(map[string]interface{})
+	data, err := ioutil.ReadFile(arcrcPath)
+	if err != nil && !os.IsNotExist(err) {
+		logger.WithError(err).Error("Unable to read .arcrc")
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			logger.WithError(err).Error("Unable to parse .arcrc")
+			return err
+		}
+	}
+	hosts, ok := cfg["hosts"].(map[string]interface{})
+	if !ok {
+		hosts = make(map[string]interface{})
+		cfg["hosts"] = hosts
+	}
+	host, ok := hosts[api].(map[string]interface{})
+	if !ok {
+		host = make(map[string]interface{})
+		hosts[api] = host
+	}
+	host["token"] = token
+
+	data, err = json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(arcrcPath), ".arcrc-*")
+	if err != nil {
+		logger.WithError(err).Error("Unable to write .arcrc")
+		return err
+	}
+	// TempFile already creates the file as 0600, but be explicit about it
+	err = tmp.Chmod(0600)
+	if err == nil {
+		_, err = tmp.Write(append(data, '\n'))
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), arcrcPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		logger.WithError(err).Error("Unable to write .arcrc")
+		return err
+	}
+	logger.Info("Token written to .arcrc")
+	return nil
+}
@@ -0,0 +1,92 @@
+package phabricator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteArcrcToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phabricator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	arcrcPath := filepath.Join(dir, ".arcrc")
+	existing := `{"hosts": {"https://old.example.com/api/": {"token": "cli-old"}}, "aliases": {"x": "y"}}`
+	if err := ioutil.WriteFile(arcrcPath, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteArcrcToken(arcrcPath, "https://phab.example.com/api/", "cli-new"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(arcrcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected .arcrc permissions 0600, got %o", perm)
+	}
+	data, err := ioutil.ReadFile(arcrcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := cfg["aliases"]; !found {
+		t.Error("Unrelated .arcrc content was dropped")
+	}
+
+	f, err := os.Open(arcrcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	arcCfg, err := arcConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token := arcCfg.Hosts["https://phab.example.com/api/"].Token; token != "cli-new" {
+		t.Errorf("Expected the new token, got %q", token)
+	}
+	if token := arcCfg.Hosts["https://old.example.com/api/"].Token; token != "cli-old" {
+		t.Errorf("Existing host token changed to %q", token)
+	}
+}
+
+func TestFindProjectConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phabricator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	nested := filepath.Join(dir, "src", "pkg")
+	if err := os.MkdirAll(nested, 0700); err != nil {
+		t.Fatal(err)
+	}
+	arcconfig := `{"phabricator.uri": "https://phab.example.com/"}`
+	if err := ioutil.WriteFile(filepath.Join(dir, ".arcconfig"), []byte(arcconfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := findProjectConfig(nested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri := configString(cfg, "phabricator.uri", "conduit_uri"); uri != "https://phab.example.com/" {
+		t.Errorf("Unexpected phabricator.uri %q", uri)
+	}
+	api, err := apiURI(configString(cfg, "phabricator.uri"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api != "https://phab.example.com/api/" {
+		t.Errorf("Unexpected API URI %q", api)
+	}
+}
@@ -0,0 +1,43 @@
+package phabricator
+
+import (
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// ttlCache is a concurrency-safe map whose entries expire after ttl.
+// A zero ttl keeps entries forever.
+type ttlCache struct {
+	lock    sync.RWMutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+}
+
+func newTTLCache(ttl time.Duration) *ttlCache {
+	return &ttlCache{ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+func (c *ttlCache) get(key string) (interface{}, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	entry, found := c.entries[key]
+	if !found || (!entry.expires.IsZero() && time.Now().After(entry.expires)) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *ttlCache) set(key string, value interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry := cacheEntry{value: value}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.entries[key] = entry
+}
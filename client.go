@@ -0,0 +1,45 @@
+package phabricator
+
+import (
+	"context"
+	"net/url"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+//go:generate moq -out phabmock/client.go -pkg phabmock . Client
+
+// Searcher calls *.search endpoints, see Phabricator.CallSearch
+// and Phabricator.SearchPage
+type Searcher interface {
+	CallSearch(ctx context.Context, endpoint string, arguments EndpointArguments, typ interface{}) <-chan interface{}
+	SearchPage(ctx context.Context, endpoint string, arguments EndpointArguments, typ interface{}, after string) (*ResultPage, error)
+}
+
+// Editor calls *.edit endpoints, see Phabricator.CallEdit
+type Editor interface {
+	CallEdit(ctx context.Context, endpoint string, arguments *EditArguments) error
+}
+
+// Caller calls any Conduit method, see Phabricator.Call
+type Caller interface {
+	Call(ctx context.Context, method string, params url.Values, result interface{}) error
+}
+
+// Client is everything Phabricator does. Code depending on it rather
+// than on *Phabricator can be tested with phabmock.ClientMock.
+type Client interface {
+	Searcher
+	Editor
+	Caller
+	WhoAmI(ctx context.Context) (*WhoAmI, error)
+	Viewer(ctx context.Context) (*WhoAmI, error)
+	Validate(ctx context.Context, endpoint string, arguments EndpointArguments) error
+	Fetch(ctx context.Context, phid phabTypes.PHID) (interface{}, error)
+	FetchAll(ctx context.Context, phids ...phabTypes.PHID) (map[phabTypes.PHID]interface{}, error)
+	TicketEnums(ctx context.Context) (*TicketEnums, error)
+	Transactions(ctx context.Context, args phabTypes.TransactionSearchArgs) ([]*phabTypes.Transaction, error)
+	ConduitURI() string
+}
+
+var _ Client = (*Phabricator)(nil)
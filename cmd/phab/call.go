@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// Where phab call reads parameters from, replaced in tests
+var stdin io.Reader = os.Stdin
+
+func runCall(ctx context.Context, args []string) error {
+	var conn connection
+	fs := newFlagSet("call", &conn)
+	method, err := parseTarget(fs, args)
+	if err != nil {
+		return err
+	}
+
+	input, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	if len(bytes.TrimSpace(input)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(input))
+		decoder.UseNumber()
+		var decoded interface{}
+		if err := decoder.Decode(&decoded); err != nil {
+			return fmt.Errorf("Invalid JSON parameters: %v", err)
+		}
+		object, isObject := decoded.(map[string]interface{})
+		if !isObject {
+			return fmt.Errorf("Parameters must be a JSON object")
+		}
+		if err := flattenParams(params, "", object); err != nil {
+			return err
+		}
+	}
+
+	phab, err := conn.connect(ctx)
+	if err != nil {
+		return err
+	}
+	var result json.RawMessage
+	if err := phab.Call(ctx, method, params, &result); err != nil {
+		return err
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, result, "", "  "); err != nil {
+		pretty.Reset()
+		pretty.Write(result)
+	}
+	pretty.WriteByte('\n')
+	_, err = pretty.WriteTo(stdout)
+	return err
+}
+
+// flattenParams encodes VALUE as form parameters the way PHP expects
+// them: nested objects and lists become key[sub][0]=value. Nulls are
+// left out. Empty lists and objects have no form encoding, so they're
+// rejected rather than silently dropped.
+func flattenParams(params url.Values, key string, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && key != "" {
+			return fmt.Errorf("Empty object %s can't be sent as form parameters", key)
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub := k
+			if key != "" {
+				sub = key + "[" + k + "]"
+			}
+			if err := flattenParams(params, sub, v[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return fmt.Errorf("Empty list %s can't be sent as form parameters", key)
+		}
+		for i, item := range v {
+			if err := flattenParams(params, fmt.Sprintf("%s[%d]", key, i), item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		params.Add(key, fmt.Sprint(v))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.showmax.cc/phabricator"
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// transactionList is a repeatable key=value flag
+type transactionList []phabricator.PhabTransaction
+
+func (l *transactionList) String() string {
+	pairs := make([]string, len(*l))
+	for i, tx := range *l {
+		pairs[i] = fmt.Sprintf("%s=%v", tx.Type, tx.Value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Set parses key=value. Values of edge transactions such as projects.add
+// are comma-separated lists.
+func (l *transactionList) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("Expected key=value, got %q", value)
+	}
+	if strings.HasSuffix(parts[0], ".add") || strings.HasSuffix(parts[0], ".remove") || strings.HasSuffix(parts[0], ".set") {
+		var values stringList
+		values.Set(parts[1])
+		*l = append(*l, phabricator.NewTransaction(parts[0], []string(values)))
+		return nil
+	}
+	*l = append(*l, phabricator.NewTransaction(parts[0], parts[1]))
+	return nil
+}
+
+func runEdit(ctx context.Context, args []string) error {
+	var conn connection
+	var transactions transactionList
+	var comment string
+	fs := newFlagSet("edit", &conn)
+	fs.Var(&transactions, "set", "Transaction as type=value, e.g. status=resolved or projects.add=PHID-PROJ-1")
+	fs.StringVar(&comment, "comment", "", "Comment to add")
+	object, err := parseTarget(fs, args)
+	if err != nil {
+		return err
+	}
+	if comment != "" {
+		transactions = append(transactions, phabricator.NewTransaction("comment", comment))
+	}
+	if len(transactions) == 0 {
+		return usagef("Nothing to edit, use --set or --comment")
+	}
+
+	phab, err := conn.connect(ctx)
+	if err != nil {
+		return err
+	}
+	phid := phabTypes.PHID(object)
+	if !phid.Valid() {
+		handles, err := phabricator.NewMonogramResolver(phab, time.Minute).Resolve(ctx, object)
+		if err != nil {
+			return err
+		}
+		handle, found := handles[object]
+		if !found {
+			return fmt.Errorf("Object %s not found", object)
+		}
+		phid = phabTypes.PHID(handle.PHID)
+	}
+	info, known := phabricator.LookupPHIDType(phid.Type())
+	if !known {
+		return fmt.Errorf("Don't know how to edit objects of type %s", phid.Type())
+	}
+	endpoint := strings.TrimSuffix(info.Endpoint, ".search") + ".edit"
+	err = phab.CallEdit(ctx, endpoint, &phabricator.EditArguments{
+		ObjectIdentifier: phid,
+		Transactions:     transactions,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Edited %s\n", object)
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+func runEndpoints(ctx context.Context, args []string) error {
+	var conn connection
+	fs := newFlagSet("endpoints", &conn)
+	if err := fs.Parse(args); err != nil {
+		return errFlags
+	}
+	phab, err := conn.connect(ctx)
+	if err != nil {
+		return err
+	}
+	names := fs.Args()
+	if len(names) == 0 {
+		if names, err = phab.Endpoints(ctx); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		description, err := phab.DescribeEndpoint(ctx, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s:\n%s", name, description)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.showmax.cc/phabricator/export"
+	"go.showmax.cc/phabricator/format"
+	"go.showmax.cc/phabricator/querylang"
+)
+
+func runExport(ctx context.Context, args []string) error {
+	var conn connection
+	var exportFormat, columns, checkpoint, query string
+	var incremental bool
+	fs := newFlagSet("export", &conn)
+	fs.StringVar(&exportFormat, "format", "ndjson", "Output format: ndjson or csv")
+	fs.StringVar(&columns, "columns", "", "CSV columns, e.g. id,fields.status.name")
+	fs.StringVar(&checkpoint, "checkpoint", "", "Checkpoint file, <output>.checkpoint by default")
+	fs.StringVar(&query, "query", "", "Only export tasks matching the query, e.g. 'project:#backend'")
+	fs.BoolVar(&incremental, "incremental", false, "Append tasks modified since the last finished export")
+	output, err := parseTarget(fs, args)
+	if err != nil {
+		return err
+	}
+	if exportFormat != "ndjson" && exportFormat != "csv" {
+		return usagef("Unknown format %q, expected ndjson or csv", exportFormat)
+	}
+
+	phab, err := conn.connect(ctx)
+	if err != nil {
+		return err
+	}
+	exporter := export.New(phab, output)
+	exporter.Format = exportFormat
+	exporter.Checkpoint = checkpoint
+	exporter.Incremental = incremental
+	if columns != "" {
+		exporter.Columns = format.ParseColumns(columns)
+	}
+	if query != "" {
+		compiler := querylang.NewCompiler(querylang.NewResolver(phab, time.Minute))
+		if exporter.Args, err = compiler.Tickets(ctx, query); err != nil {
+			return err
+		}
+	}
+	n, err := exporter.Run(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Exported %d tasks to %s\n", n, output)
+	return nil
+}
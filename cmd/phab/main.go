@@ -0,0 +1,232 @@
+// Command phab is a command line client for the Conduit API.
+//
+//	phab search maniphest --status open --owner me --format table
+//	phab search maniphest --format csv --columns id,fields.status.name,fields.name
+//	phab edit T123 --set status=resolved --comment "Fixed in D45"
+//	phab whoami
+//	phab endpoints [endpoint...]
+//	echo '{"names": ["T1"]}' | phab call phid.lookup
+//	phab export tasks.ndjson --incremental
+//
+// Credentials are read like the library does by default: from
+// $PHABRICATOR_TOKEN and $PHABRICATOR_API, then from ~/.arcrc.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.showmax.cc/phabricator"
+)
+
+// Where command output goes, replaced in tests
+var stdout io.Writer = os.Stdout
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+	// Phabricator rejected the call, e.g. with ERR-INVALID-AUTH
+	exitConduit = 3
+	// The call didn't get through: network, HTTP or response decoding errors
+	exitTransport = 4
+)
+
+// Usage of the commands, in the order they're listed in help
+var usages = []struct {
+	name, usage string
+}{
+	{"search", "search <maniphest|differential|project|user|diffusion> [flags]"},
+	{"edit", "edit <object> --set key=value... [--comment text]"},
+	{"whoami", "whoami [--format table|csv|json|ndjson|template]"},
+	{"endpoints", "endpoints [endpoint...]"},
+	{"call", "call <method> < params.json"},
+	{"export", "export <output> [--format ndjson|csv] [--incremental]"},
+}
+
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"search":    runSearch,
+	"edit":      runEdit,
+	"whoami":    runWhoami,
+	"endpoints": runEndpoints,
+	"call":      runCall,
+	"export":    runExport,
+}
+
+func commandUsage(name string) string {
+	for _, u := range usages {
+		if u.name == name {
+			return u.usage
+		}
+	}
+	return name
+}
+
+// errFlags is returned when parsing flags failed or help was requested
+var errFlags = errors.New("Invalid flags")
+
+// newFlagSet creates the flag set of command NAME with the shared flags
+func newFlagSet(name string, conn *connection) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: phab %s\n\nFlags:\n", commandUsage(name))
+		fs.PrintDefaults()
+	}
+	conn.register(fs)
+	return fs
+}
+
+// usageError is reported with exit code 2
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// connection holds the flags shared by all commands
+type connection struct {
+	api      string
+	token    string
+	logLevel string
+	timeout  time.Duration
+	retries  int
+}
+
+func (c *connection) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.api, "api", "", "Conduit URI, e.g. https://phab.example.com/api/")
+	fs.StringVar(&c.token, "token", "", "API token, requires --api")
+	fs.StringVar(&c.logLevel, "log-level", "error", "Log level of the library")
+	fs.DurationVar(&c.timeout, "timeout", 30*time.Second, "Timeout of a single request")
+	// Edits and arbitrary calls may not be idempotent, so they aren't
+	// repeated after a timeout unless asked for
+	retries := 2
+	if name := fs.Name(); name == "edit" || name == "call" {
+		retries = 0
+	}
+	fs.IntVar(&c.retries, "retries", retries, "Retries of requests failing with transport errors")
+}
+
+// connect creates the client, authenticating like phabricator.Init
+func (c *connection) connect(ctx context.Context) (*phabricator.Phabricator, error) {
+	opts := []phabricator.Option{
+		phabricator.WithLogLevel(c.logLevel),
+		phabricator.WithLogOutput(os.Stderr),
+		phabricator.WithTimeout(c.timeout),
+		phabricator.WithRetries(c.retries, time.Second),
+	}
+	if c.api != "" {
+		opts = append(opts, phabricator.WithAPI(c.api))
+	}
+	if c.token != "" {
+		opts = append(opts, phabricator.WithToken(c.token))
+	}
+	return phabricator.New(ctx, opts...)
+}
+
+// stringList is a flag that can be repeated and takes comma-separated values
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// parseTarget parses ARGS of a command taking a single positional
+// argument, which may come before or after the flags
+func parseTarget(fs *flag.FlagSet, args []string) (string, error) {
+	var target string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		target, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", errFlags
+	}
+	if target == "" && fs.NArg() > 0 {
+		target = fs.Arg(0)
+		if fs.NArg() > 1 {
+			return "", usagef("Unexpected arguments %v", fs.Args()[1:])
+		}
+	} else if fs.NArg() > 0 {
+		return "", usagef("Unexpected arguments %v", fs.Args())
+	}
+	if target == "" {
+		return "", usagef("Missing argument")
+	}
+	return target, nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: phab <command> [flags]\n\nCommands:")
+	for _, u := range usages {
+		fmt.Fprintf(os.Stderr, "  phab %s\n", u.usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun phab <command> -h for the flags of a command.")
+}
+
+func run(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage()
+		return exitUsage
+	}
+	runCommand, known := commands[args[0]]
+	if !known {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+		usage()
+		return exitUsage
+	}
+	if err := runCommand(context.Background(), args[1:]); err != nil {
+		if err == errFlags {
+			// The flag package already printed the problem and usage
+			return exitUsage
+		}
+		fmt.Fprintf(os.Stderr, "phab %s: %s\n", args[0], err)
+		return exitCode(err, args[0])
+	}
+	return exitOK
+}
+
+// exitCode reports ERR of COMMAND and picks the exit code
+func exitCode(err error, command string) int {
+	var usageErr *usageError
+	var conduitErr *phabricator.ConduitError
+	var httpErr *phabricator.HTTPError
+	var urlErr *url.Error
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(os.Stderr, "Usage: phab %s\n", commandUsage(command))
+		return exitUsage
+	case errors.As(err, &conduitErr):
+		return exitConduit
+	case errors.As(err, &httpErr), errors.As(err, &urlErr), errors.As(err, &syntaxErr):
+		return exitTransport
+	}
+	return exitError
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.showmax.cc/phabricator/phabtest"
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func testServer(t *testing.T) *phabtest.Server {
+	t.Helper()
+	server := phabtest.NewServer()
+	var open, resolved phabTypes.Ticket
+	open.Fields.Name = "Open task"
+	open.Fields.Status.Value = phabTypes.TicketStatusOpen
+	resolved.Fields.Name = "Resolved task"
+	resolved.Fields.Status.Value = phabTypes.TicketStatusResolved
+	if err := server.Add("maniphest.search", open, resolved); err != nil {
+		t.Fatal(err)
+	}
+	server.Handle("maniphest.status.search", func(url.Values) (interface{}, error) {
+		return map[string]interface{}{"data": []map[string]interface{}{
+			{"value": "open", "name": "Open"},
+			{"value": "resolved", "name": "Resolved", "closed": true},
+		}}, nil
+	})
+	server.Handle("maniphest.priority.search", func(url.Values) (interface{}, error) {
+		return map[string]interface{}{"data": []interface{}{}}, nil
+	})
+	server.Handle("phid.lookup", func(params url.Values) (interface{}, error) {
+		return map[string]interface{}{
+			params.Get("names[0]"): map[string]string{"phid": "PHID-TASK-1", "type": "TASK", "name": "T1"},
+		}, nil
+	})
+	return server
+}
+
+func runCaptured(args ...string) (int, string) {
+	var out bytes.Buffer
+	stdout = &out
+	code := run(args)
+	return code, out.String()
+}
+
+func TestSearch(t *testing.T) {
+	server := testServer(t)
+	defer server.Close()
+
+	code, out := runCaptured("search", "maniphest", "--api", server.API(), "--token", "api-test",
+		"--status", "open", "--format", "csv")
+	if code != exitOK {
+		t.Fatalf("Exit code %d", code)
+	}
+	if out != "id,status,priority,title\nT1,open,,Open task\n" {
+		t.Errorf("Unexpected output %q", out)
+	}
+
+	code, out = runCaptured("search", "maniphest", "--api", server.API(), "--token", "api-test")
+	if code != exitOK || !strings.Contains(out, "T2  ") || strings.Index(out, "T2") > strings.Index(out, "T1") {
+		t.Errorf("Unexpected table (%d):\n%s", code, out)
+	}
+
+	code, out = runCaptured("search", "maniphest", "--api", server.API(), "--token", "api-test",
+		"--format", "csv", "--columns", "id,fields.status.value")
+	if code != exitOK || out != "id,fields.status.value\n2,resolved\n1,open\n" {
+		t.Errorf("Unexpected columns (%d): %q", code, out)
+	}
+	code, out = runCaptured("search", "maniphest", "--api", server.API(), "--token", "api-test",
+		"--template", "{{.Id}}: {{.Fields.Name}}")
+	if code != exitOK || out != "2: Resolved task\n1: Open task\n" {
+		t.Errorf("Unexpected template output (%d): %q", code, out)
+	}
+	if code, _ = runCaptured("search", "maniphest", "--format", "xml"); code != exitUsage {
+		t.Errorf("Unknown format exited with %d", code)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	server := testServer(t)
+	defer server.Close()
+
+	code, out := runCaptured("edit", "T1", "--api", server.API(), "--token", "api-test",
+		"--set", "status=resolved", "--comment", "Done & dusted")
+	if code != exitOK || out != "Edited T1\n" {
+		t.Fatalf("Edit failed (%d): %s", code, out)
+	}
+	edits := server.Edits()
+	if len(edits) != 1 || edits[0].ObjectPHID != "PHID-TASK-1" || edits[0].Transactions[1].Value != "Done & dusted" {
+		t.Errorf("Unexpected edits %+v", edits)
+	}
+}
+
+func TestEditUneditable(t *testing.T) {
+	server := testServer(t)
+	defer server.Close()
+
+	// Neither user.edit nor differential.diff.edit exist
+	for _, object := range []string{"PHID-USER-1", "PHID-DIFF-1"} {
+		code, out := runCaptured("edit", object, "--api", server.API(), "--token", "api-test",
+			"--comment", "Hello")
+		if code == exitOK || out != "" {
+			t.Errorf("Editing %s exited with %d: %s", object, code, out)
+		}
+	}
+	if edits := server.Edits(); len(edits) != 0 {
+		t.Errorf("Unexpected edits %+v", edits)
+	}
+}
+
+func TestUsages(t *testing.T) {
+	listed := make(map[string]bool)
+	for _, u := range usages {
+		listed[u.name] = true
+	}
+	for name := range commands {
+		if !listed[name] {
+			t.Errorf("Command %s missing from usage", name)
+		}
+	}
+}
+
+func TestRetriesDefault(t *testing.T) {
+	for name, expected := range map[string]string{"search": "2", "edit": "0", "call": "0"} {
+		var conn connection
+		if retries := newFlagSet(name, &conn).Lookup("retries").DefValue; retries != expected {
+			t.Errorf("%s retries %s times by default", name, retries)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	server := testServer(t)
+	defer server.Close()
+
+	if code, _ := runCaptured("frobnicate"); code != exitUsage {
+		t.Errorf("Unknown command exited with %d", code)
+	}
+	if code, _ := runCaptured("search", "phriction", "--api", server.API(), "--token", "api-test"); code != exitUsage {
+		t.Errorf("Unknown application exited with %d", code)
+	}
+	for _, args := range [][]string{
+		{"differential", "--owner", "me"},
+		{"differential", "--priority", "high"},
+		{"maniphest", "--reviewer", "alice"},
+		{"project", "--status", "open"},
+		{"user", "--status", "open"},
+	} {
+		args = append([]string{"search"}, append(args, "--api", server.API(), "--token", "api-test")...)
+		if code, _ := runCaptured(args...); code != exitUsage {
+			t.Errorf("%v exited with %d", args, code)
+		}
+	}
+	server.FailNext("user.whoami", "ERR-INVALID-AUTH", "Bad token")
+	if code, _ := runCaptured("whoami", "--api", server.API(), "--token", "api-test"); code != exitConduit {
+		t.Errorf("Conduit error exited with %d", code)
+	}
+	server.FailNextHTTP("user.whoami", 500)
+	if code, _ := runCaptured("whoami", "--api", server.API(), "--token", "api-test", "--retries", "0"); code != exitTransport {
+		t.Errorf("HTTP error exited with %d", code)
+	}
+}
+
+func TestCall(t *testing.T) {
+	server := testServer(t)
+	defer server.Close()
+
+	stdin = strings.NewReader(`{"names": ["T1"], "nested": {"flag": true, "ids": [1, 2]}}`)
+	code, out := runCaptured("call", "phid.lookup", "--api", server.API(), "--token", "api-test")
+	if code != exitOK || !strings.Contains(out, `"phid": "PHID-TASK-1"`) {
+		t.Errorf("Unexpected result (%d): %s", code, out)
+	}
+	calls := server.Calls()
+	params := calls[len(calls)-1].Params
+	if params.Get("names[0]") != "T1" || params.Get("nested[flag]") != "true" || params.Get("nested[ids][1]") != "2" {
+		t.Errorf("Parameters not flattened: %v", params)
+	}
+
+	stdin = strings.NewReader(`{"names": ["T1"], "limit": null}`)
+	if code, _ := runCaptured("call", "phid.lookup", "--api", server.API(), "--token", "api-test"); code != exitOK {
+		t.Errorf("Null parameter exited with %d", code)
+	}
+	calls = server.Calls()
+	if params := calls[len(calls)-1].Params; params["limit"] != nil {
+		t.Errorf("Null parameter sent: %v", params)
+	}
+	for _, input := range []string{`{"constraints": {"phids": []}}`, `{"constraints": {}}`} {
+		before := len(server.Calls())
+		stdin = strings.NewReader(input)
+		if code, _ := runCaptured("call", "maniphest.search", "--api", server.API(), "--token", "api-test"); code != exitError {
+			t.Errorf("%s exited with %d", input, code)
+		}
+		if len(server.Calls()) != before {
+			t.Errorf("%s was sent", input)
+		}
+	}
+
+	stdin = strings.NewReader(`{names}`)
+	if code, _ := runCaptured("call", "phid.lookup", "--api", server.API(), "--token", "api-test"); code != exitError {
+		t.Errorf("Invalid JSON exited with %d", code)
+	}
+	stdin = strings.NewReader("")
+	if code, _ := runCaptured("call", "no.such.method", "--api", server.API(), "--token", "api-test"); code != exitConduit {
+		t.Errorf("Unknown method exited with %d", code)
+	}
+}
+
+func TestExport(t *testing.T) {
+	server := testServer(t)
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "phab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "tasks.csv")
+
+	code, out := runCaptured("export", output, "--api", server.API(), "--token", "api-test",
+		"--format", "csv", "--columns", "id,fields.name", "--query", "status:open")
+	if code != exitOK || out != "Exported 1 tasks to "+output+"\n" {
+		t.Fatalf("Export failed (%d): %s", code, out)
+	}
+	if data, _ := ioutil.ReadFile(output); string(data) != "id,fields.name\n1,Open task\n" {
+		t.Errorf("Unexpected export %q", data)
+	}
+	if _, err := os.Stat(output + ".checkpoint"); err != nil {
+		t.Errorf("No checkpoint: %v", err)
+	}
+}
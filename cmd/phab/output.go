@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"go.showmax.cc/phabricator/format"
+)
+
+// outputFlags select how commands listing objects print them
+type outputFlags struct {
+	format   string
+	columns  string
+	template string
+}
+
+func (o *outputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "table", "Output format: "+strings.Join(format.Formats, ", "))
+	fs.StringVar(&o.columns, "columns", "", "Table and CSV columns, e.g. id,fields.status.name")
+	fs.StringVar(&o.template, "template", "", "Go template of each object for --format template, e.g. '{{.Id}} {{.Fields.Name}}'")
+}
+
+// writer creates the selected format.Writer. Tables and CSV use
+// DEFAULTS unless --columns is given.
+func (o *outputFlags) writer(defaults []format.Column) (format.Writer, error) {
+	columns := defaults
+	if o.columns != "" {
+		columns = format.ParseColumns(o.columns)
+	}
+	if o.template != "" && o.format == "table" {
+		o.format = "template"
+	}
+	writer, err := format.New(stdout, o.format, columns, o.template)
+	if err != nil {
+		return nil, usagef("%v", err)
+	}
+	return writer, nil
+}
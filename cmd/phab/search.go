@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.showmax.cc/phabricator"
+	"go.showmax.cc/phabricator/diffusion"
+	"go.showmax.cc/phabricator/format"
+	"go.showmax.cc/phabricator/project"
+	"go.showmax.cc/phabricator/querylang"
+	phabTypes "go.showmax.cc/phabricator/types"
+	"go.showmax.cc/phabricator/user"
+)
+
+// searchFlags are the flags of phab search. Constraints of tasks and
+// revisions are turned into a querylang query, so they accept the same
+// values: me, usernames, project slugs, 7d or 2006-01-02 etc.
+type searchFlags struct {
+	conn        connection
+	output      outputFlags
+	limit       int
+	order       string
+	query       string
+	status      stringList
+	owner       stringList
+	author      stringList
+	reviewer    stringList
+	subscriber  stringList
+	project     stringList
+	priority    stringList
+	repository  stringList
+	created     string
+	modified    string
+	names       stringList
+	constraints []string
+}
+
+// constrain adds KEY with VALUES to the querylang query
+func (f *searchFlags) constrain(key string, values ...string) {
+	if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+		return
+	}
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = value
+		if strings.ContainsAny(value, " \t,") {
+			quoted[i] = `"` + value + `"`
+		}
+	}
+	f.constraints = append(f.constraints, key+":"+strings.Join(quoted, ","))
+}
+
+// text returns the querylang query built from the flags
+func (f *searchFlags) text() string {
+	return strings.TrimSpace(strings.Join(f.constraints, " ") + " " + f.query)
+}
+
+// constraintFlags lists the applications each constraint flag applies to
+var constraintFlags = map[string][]string{
+	"status":     {"maniphest", "differential"},
+	"owner":      {"maniphest"},
+	"author":     {"maniphest", "differential"},
+	"reviewer":   {"differential"},
+	"subscriber": {"maniphest", "differential"},
+	"project":    {"maniphest", "differential"},
+	"priority":   {"maniphest"},
+	"repository": {"differential"},
+	"created":    {"maniphest", "differential"},
+	"modified":   {"maniphest"},
+	"name":       {"project", "user", "diffusion"},
+}
+
+// checkConstraints fails if a constraint flag set in FS doesn't apply
+// to application APP, instead of silently ignoring it
+func checkConstraints(fs *flag.FlagSet, app string) error {
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		apps, constraint := constraintFlags[fl.Name]
+		if !constraint || err != nil {
+			return
+		}
+		for _, applies := range apps {
+			if applies == app {
+				return
+			}
+		}
+		err = usagef("--%s doesn't apply to %s, only to %s", fl.Name, app, strings.Join(apps, ", "))
+	})
+	return err
+}
+
+// searchApp describes how to search one application
+type searchApp struct {
+	endpoint string
+	result   interface{}
+	columns  []format.Column
+}
+
+var searchApps = map[string]searchApp{
+	"maniphest": {"maniphest.search", phabTypes.Ticket{}, []format.Column{
+		{Header: "id", Value: func(o interface{}) string { return fmt.Sprintf("T%d", o.(*phabTypes.Ticket).Id) }},
+		{Header: "status", Value: func(o interface{}) string { return string(o.(*phabTypes.Ticket).Fields.Status.Value) }},
+		{Header: "priority", Value: func(o interface{}) string { return o.(*phabTypes.Ticket).Fields.Priority.Name }},
+		{Header: "title", Value: func(o interface{}) string { return o.(*phabTypes.Ticket).Fields.Name }},
+	}},
+	"differential": {"differential.revision.search", phabTypes.Revision{}, []format.Column{
+		{Header: "id", Value: func(o interface{}) string { return fmt.Sprintf("D%d", o.(*phabTypes.Revision).Id) }},
+		{Header: "status", Value: func(o interface{}) string { return string(o.(*phabTypes.Revision).Fields.Status.Value) }},
+		{Header: "title", Value: func(o interface{}) string { return o.(*phabTypes.Revision).Fields.Title }},
+	}},
+	"project": {"project.search", phabTypes.Project{}, []format.Column{
+		{Header: "id", Value: func(o interface{}) string { return fmt.Sprint(o.(*phabTypes.Project).Id) }},
+		{Header: "slug", Value: func(o interface{}) string { return o.(*phabTypes.Project).Fields.Slug }},
+		{Header: "name", Value: func(o interface{}) string { return o.(*phabTypes.Project).Fields.Name }},
+	}},
+	"user": {"user.search", phabTypes.User{}, []format.Column{
+		{Header: "username", Value: func(o interface{}) string { return o.(*phabTypes.User).Fields.Username }},
+		{Header: "name", Value: func(o interface{}) string { return o.(*phabTypes.User).Fields.RealName }},
+		{Header: "roles", Value: func(o interface{}) string { return strings.Join(o.(*phabTypes.User).Fields.Roles, ",") }},
+	}},
+	"diffusion": {"diffusion.repository.search", phabTypes.Repository{}, []format.Column{
+		{Header: "id", Value: func(o interface{}) string { return fmt.Sprintf("R%d", o.(*phabTypes.Repository).Id) }},
+		{Header: "callsign", Value: func(o interface{}) string { return o.(*phabTypes.Repository).Fields.Callsign }},
+		{Header: "vcs", Value: func(o interface{}) string { return o.(*phabTypes.Repository).Fields.Vcs }},
+		{Header: "name", Value: func(o interface{}) string { return o.(*phabTypes.Repository).Fields.Name }},
+	}},
+}
+
+func runSearch(ctx context.Context, args []string) error {
+	var f searchFlags
+	fs := newFlagSet("search", &f.conn)
+	f.output.register(fs)
+	fs.IntVar(&f.limit, "limit", 0, "Maximum number of results, all if 0")
+	fs.StringVar(&f.order, "order", "", "Result order, e.g. priority or updated")
+	fs.StringVar(&f.query, "query", "", "Query such as 'status:open owner:@me crash', full-text for project, user and diffusion")
+	fs.Var(&f.status, "status", "Task or revision statuses")
+	fs.Var(&f.owner, "owner", "Task owners, me for yourself, none for unassigned")
+	fs.Var(&f.author, "author", "Authors of tasks or revisions")
+	fs.Var(&f.reviewer, "reviewer", "Revision reviewers")
+	fs.Var(&f.subscriber, "subscriber", "Subscribers of tasks or revisions")
+	fs.Var(&f.project, "project", "Project slugs tasks or revisions are tagged with")
+	fs.Var(&f.priority, "priority", "Task priorities, e.g. high")
+	fs.Var(&f.repository, "repository", "Revision repositories, e.g. rXYZ")
+	fs.StringVar(&f.created, "created", "", "Creation time, e.g. >7d, <2020-01-31 or 2020-01-31")
+	fs.StringVar(&f.modified, "modified", "", "Modification time of tasks, like --created")
+	fs.Var(&f.names, "name", "Project slugs, usernames or repository callsigns")
+	appName, err := parseTarget(fs, args)
+	if err != nil {
+		return err
+	}
+	app, known := searchApps[appName]
+	if !known {
+		return usagef("Unknown application %q", appName)
+	}
+	if err := checkConstraints(fs, appName); err != nil {
+		return err
+	}
+	writer, err := f.output.writer(app.columns)
+	if err != nil {
+		return err
+	}
+
+	phab, err := f.conn.connect(ctx)
+	if err != nil {
+		return err
+	}
+	arguments, err := f.arguments(ctx, phab, appName)
+	if err != nil {
+		if qerr, ok := err.(*querylang.Error); ok {
+			return fmt.Errorf("%s\n%s", qerr, qerr.Pointer())
+		}
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := phab.CallSearch(ctx, app.endpoint, arguments, app.result)
+	if results == nil {
+		return fmt.Errorf("Endpoint %s is not available", app.endpoint)
+	}
+	var objects []interface{}
+	for result := range results {
+		if err, isErr := result.(error); isErr {
+			return err
+		}
+		objects = append(objects, result)
+		if f.limit > 0 && len(objects) == f.limit {
+			cancel()
+			break
+		}
+	}
+	if f.order == "" {
+		sortByID(objects)
+	}
+	for _, object := range objects {
+		if err := writer.Write(object); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// arguments builds the search arguments of application APP from the flags
+func (f *searchFlags) arguments(ctx context.Context, phab *phabricator.Phabricator, app string) (phabricator.EndpointArguments, error) {
+	compiler := querylang.NewCompiler(querylang.NewResolver(phab, time.Minute))
+	f.constrain("status", f.status...)
+	f.constrain("author", f.author...)
+	f.constrain("subscriber", f.subscriber...)
+	f.constrain("project", f.project...)
+	f.constrain("created", f.created)
+	f.constrain("order", f.order)
+	switch app {
+	case "maniphest":
+		f.constrain("owner", f.owner...)
+		f.constrain("priority", f.priority...)
+		f.constrain("modified", f.modified)
+		return compiler.Tickets(ctx, f.text())
+	case "differential":
+		f.constrain("reviewer", f.reviewer...)
+		f.constrain("repository", f.repository...)
+		return compiler.Revisions(ctx, f.text())
+	case "project":
+		return project.Query().Slugs(f.names...).Matching(f.query).Order(f.order).Args(), nil
+	case "user":
+		return user.Query().Usernames(f.names...).Matching(f.query).Order(f.order).Args(), nil
+	case "diffusion":
+		return diffusion.Query().Callsigns(f.names...).Matching(f.query).Order(f.order).Args(), nil
+	}
+	return nil, usagef("Unknown application %q", app)
+}
+
+// sortByID orders search results newest first. Pages are decoded
+// concurrently, so results don't arrive in order.
+func sortByID(objects []interface{}) {
+	id := func(object interface{}) int64 {
+		field := reflect.Indirect(reflect.ValueOf(object)).FieldByName("Id")
+		if field.IsValid() && field.Kind() == reflect.Int {
+			return field.Int()
+		}
+		return 0
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return id(objects[i]) > id(objects[j])
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.showmax.cc/phabricator"
+	"go.showmax.cc/phabricator/format"
+)
+
+var whoamiColumns = []format.Column{
+	{Header: "username", Value: func(o interface{}) string { return o.(*phabricator.WhoAmI).Username }},
+	{Header: "name", Value: func(o interface{}) string { return o.(*phabricator.WhoAmI).RealName }},
+	{Header: "phid", Value: func(o interface{}) string { return o.(*phabricator.WhoAmI).PHID }},
+	{Header: "roles", Value: func(o interface{}) string { return strings.Join(o.(*phabricator.WhoAmI).Roles, ",") }},
+}
+
+func runWhoami(ctx context.Context, args []string) error {
+	var conn connection
+	var output outputFlags
+	fs := newFlagSet("whoami", &conn)
+	output.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return errFlags
+	}
+	if fs.NArg() > 0 {
+		return usagef("Unexpected arguments %v", fs.Args())
+	}
+	writer, err := output.writer(whoamiColumns)
+	if err != nil {
+		return err
+	}
+	phab, err := conn.connect(ctx)
+	if err != nil {
+		return err
+	}
+	who, err := phab.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	if output.format != "table" || output.columns != "" {
+		if err := writer.Write(who); err != nil {
+			return err
+		}
+		return writer.Flush()
+	}
+	fmt.Fprintf(stdout, "%s (%s)\n%s\nRoles: %s\n", who.Username, who.RealName, who.PHID, strings.Join(who.Roles, ", "))
+	return nil
+}
@@ -0,0 +1,106 @@
+package phabricator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+)
+
+// ConduitError is an error reported by Phabricator itself, as opposed
+// to transport or decoding errors. Code is e.g. ERR-INVALID-AUTH.
+type ConduitError struct {
+	Code string
+	Info string
+}
+
+func (e *ConduitError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Code, e.Info)
+}
+
+// conduitError turns a Conduit error response into an error.
+// Phabricator sometimes echoes request parameters in the error info,
+// so it's scrubbed of credentials.
+func conduitError(code, info string) error {
+	return &ConduitError{Code: code, Info: Redact(info)}
+}
+
+// baseResponse is the envelope of every Conduit response
+type baseResponse struct {
+	Result    json.RawMessage `json:"result"`
+	ErrorCode string          `json:"error_code"`
+	ErrorInfo string          `json:"error_info"`
+}
+
+// call invokes METHOD with the form-encoded PARAMS and decodes
+// the result into RESULT. Unlike CallSearch and CallEdit, it doesn't
+// need the endpoints to be discovered.
+func (p *Phabricator) call(ctx context.Context, method, params string, result interface{}) error {
+	if p.apiEndpoint == nil {
+		msg := "Phabricator instance is not initialized"
+		logger.Error(msg)
+		return errors.New(msg)
+	}
+	if err := p.ensureSession(ctx); err != nil {
+		return err
+	}
+	path, _ := url.Parse(method)
+	fullEndpoint := p.apiEndpoint.ResolveReference(path).String()
+
+	body, err := p.postRequest(ctx, fullEndpoint, params)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"error":    err,
+			"endpoint": method,
+		}).Error("Request to Phabricator failed")
+		return err
+	}
+	logger.WithField("body", string(body)).Debug("Response")
+	var resp baseResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		logger.WithFields(log.Fields{
+			"error":    err,
+			"endpoint": method,
+		}).Error("Failed to decode JSON")
+		return err
+	}
+	if resp.ErrorCode != "" {
+		logger.WithFields(log.Fields{
+			"PhabricatorErrorCode": resp.ErrorCode,
+			"PhabricatorErrorInfo": resp.ErrorInfo,
+		}).Error("Invalid Phabricator Request")
+		return conduitError(resp.ErrorCode, resp.ErrorInfo)
+	}
+	if result == nil {
+		return nil
+	}
+	// PHP serializes empty maps as empty lists
+	if bytes.Equal(bytes.TrimSpace(resp.Result), []byte("[]")) &&
+		reflect.Indirect(reflect.ValueOf(result)).Kind() == reflect.Map {
+		return nil
+	}
+	if norm, exists := normalization[method]; exists {
+		resp.Result = bytes.Replace(resp.Result, norm.from, norm.to, -1)
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		logger.WithFields(log.Fields{
+			"error":    err,
+			"endpoint": method,
+		}).Error("Failed to decode the result")
+		return err
+	}
+	return nil
+}
+
+// Call invokes any Conduit METHOD with PARAMS and decodes the result
+// into RESULT, which may be nil if the result isn't needed.
+// Errors reported by Phabricator are returned as *ConduitError.
+func (p *Phabricator) Call(ctx context.Context, method string, params url.Values, result interface{}) error {
+	return p.call(ctx, method, params.Encode(), result)
+}
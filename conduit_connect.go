@@ -0,0 +1,121 @@
+package phabricator
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	conduitClientName    = "go-phabricator"
+	conduitClientVersion = 6 // Same as arc at the time certificates were retired
+)
+
+// conduitSession is what conduit.connect returns
+// for certificate-based authentication
+type conduitSession struct {
+	ConnectionID int64  `json:"connectionID"`
+	SessionKey   string `json:"sessionKey"`
+	UserPHID     string `json:"userPHID"`
+}
+
+type conduitConnectResponse struct {
+	Result    conduitSession `json:"result"`
+	ErrorCode string         `json:"error_code"`
+	ErrorInfo string         `json:"error_info"`
+}
+
+// authValues returns the POST parameters that authenticate a request
+func (p *Phabricator) authValues() url.Values {
+	if p.session != nil {
+		// Conduit reads request metadata of form-encoded
+		// requests from parameters prefixed with api.
+		return url.Values{
+			"api.sessionKey":   {p.session.SessionKey},
+			"api.connectionID": {strconv.FormatInt(p.session.ConnectionID, 10)},
+		}
+	}
+	if p.accessToken != "" {
+		return url.Values{"access_token": {p.accessToken}}
+	}
+	return url.Values{"api.token": {p.apiToken}}
+}
+
+// ensureSession connects if certificate authentication is used
+// and there's no session yet
+func (p *Phabricator) ensureSession(ctx context.Context) error {
+	if p.certificate == "" {
+		return nil
+	}
+	p.sessionLock.Lock()
+	defer p.sessionLock.Unlock()
+	if p.session != nil {
+		return nil
+	}
+	return p.connect(ctx)
+}
+
+// connect establishes a Conduit session using the legacy user/certificate
+// pair from .arcrc. Phabricator signs the session with
+// sha1(authToken + certificate), where authToken is the current time.
+func (p *Phabricator) connect(ctx context.Context) error {
+	endpoint := "conduit.connect"
+	path, _ := url.Parse(endpoint)
+	fullEndpoint := p.apiEndpoint.ResolveReference(path).String()
+
+	authToken := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := sha1.Sum([]byte(authToken + p.certificate))
+	params, err := json.Marshal(map[string]interface{}{
+		"client":        conduitClientName,
+		"clientVersion": conduitClientVersion,
+		"host":          p.ConduitURI(),
+		"user":          p.certUser,
+		"authToken":     authToken,
+		"authSignature": hex.EncodeToString(signature[:]),
+	})
+	if err != nil {
+		return err
+	}
+	data := url.Values{
+		"params":      {string(params)},
+		"output":      {"json"},
+		"__conduit__": {"true"},
+	}
+
+	// The session is authenticated by its parameters,
+	// so there's nothing for postRequest to add
+	body, err := p.post(ctx, fullEndpoint, data.Encode())
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"error":    err,
+			"endpoint": endpoint,
+		}).Error("Request to Phabricator failed")
+		return err
+	}
+	var resp conduitConnectResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		logger.WithError(err).Error("Failed to decode JSON")
+		return err
+	}
+	if resp.ErrorCode != "" {
+		logger.WithFields(log.Fields{
+			"PhabricatorErrorCode": resp.ErrorCode,
+			"PhabricatorErrorInfo": resp.ErrorInfo,
+		}).Error("Certificate authentication failed")
+		return conduitError(resp.ErrorCode, resp.ErrorInfo)
+	}
+	logger.WithFields(log.Fields{
+		"user":          p.certUser,
+		"connection_id": resp.Result.ConnectionID,
+	}).Debug("Conduit session established")
+	registerSecret(resp.Result.SessionKey)
+	p.session = &resp.Result
+	return nil
+}
@@ -0,0 +1,198 @@
+// Package differential builds arguments for differential.revision.search
+// and differential.diff.search
+//
+//	args := differential.Query().
+//		Status(phabTypes.RevisionStatusNeedsReview).
+//		ReviewedBy(me).
+//		WithReviewers().
+//		Args()
+//	results := phab.CallSearch(ctx, differential.Endpoint, args, phabTypes.Revision{})
+package differential
+
+import (
+	"time"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// Endpoints the built arguments are meant for
+const (
+	Endpoint     = "differential.revision.search"
+	DiffEndpoint = "differential.diff.search"
+)
+
+// Builder builds RevisionSearchArgs. Every method modifies the builder
+// and returns it, so calls can be chained.
+type Builder struct {
+	args phabTypes.RevisionSearchArgs
+}
+
+// Query starts a new, unconstrained revision query
+func Query() *Builder {
+	return &Builder{}
+}
+
+// Args returns the built arguments
+func (b *Builder) Args() phabTypes.RevisionSearchArgs {
+	return b.args
+}
+
+// QueryKey starts from a saved or builtin query, e.g. active or authored
+func (b *Builder) QueryKey(key string) *Builder {
+	b.args.QueryKey = key
+	return b
+}
+
+// IDs limits the results to revisions with IDS
+func (b *Builder) IDs(ids ...int) *Builder {
+	b.args.Constraints.Ids = append(b.args.Constraints.Ids, ids...)
+	return b
+}
+
+// PHIDs limits the results to revisions with PHIDS
+func (b *Builder) PHIDs(phids ...string) *Builder {
+	b.args.Constraints.Phids = append(b.args.Constraints.Phids, phids...)
+	return b
+}
+
+// Status limits the results to revisions in any of STATUSES
+func (b *Builder) Status(statuses ...phabTypes.RevisionStatus) *Builder {
+	b.args.Constraints.Statuses = append(b.args.Constraints.Statuses, statuses...)
+	return b
+}
+
+// AuthoredBy limits the results to revisions created by any of the users with PHIDS
+func (b *Builder) AuthoredBy(phids ...string) *Builder {
+	b.args.Constraints.AuthorPHIDs = append(b.args.Constraints.AuthorPHIDs, phids...)
+	return b
+}
+
+// ReviewedBy limits the results to revisions with any of PHIDS as a reviewer
+func (b *Builder) ReviewedBy(phids ...string) *Builder {
+	b.args.Constraints.ReviewerPHIDs = append(b.args.Constraints.ReviewerPHIDs, phids...)
+	return b
+}
+
+// RespondedBy limits the results to revisions any of PHIDS is responsible for,
+// either as the author or as a reviewer
+func (b *Builder) RespondedBy(phids ...string) *Builder {
+	b.args.Constraints.ResponsiblePHIDs = append(b.args.Constraints.ResponsiblePHIDs, phids...)
+	return b
+}
+
+// InRepositories limits the results to revisions in any of the repositories with PHIDS
+func (b *Builder) InRepositories(phids ...string) *Builder {
+	b.args.Constraints.RepositoryPHIDs = append(b.args.Constraints.RepositoryPHIDs, phids...)
+	return b
+}
+
+// SubscribedBy limits the results to revisions any of PHIDS is subscribed to
+func (b *Builder) SubscribedBy(phids ...string) *Builder {
+	b.args.Constraints.Subscribers = append(b.args.Constraints.Subscribers, phids...)
+	return b
+}
+
+// InProjects limits the results to revisions tagged with all of the projects with PHIDS
+func (b *Builder) InProjects(phids ...string) *Builder {
+	b.args.Constraints.Projects = append(b.args.Constraints.Projects, phids...)
+	return b
+}
+
+// Matching limits the results to revisions matching the full-text query TEXT
+func (b *Builder) Matching(text string) *Builder {
+	b.args.Constraints.Query = text
+	return b
+}
+
+// CreatedAfter limits the results to revisions created at or after T
+func (b *Builder) CreatedAfter(t time.Time) *Builder {
+	b.args.Constraints.CreatedStart = t.Unix()
+	return b
+}
+
+// CreatedBefore limits the results to revisions created at or before T
+func (b *Builder) CreatedBefore(t time.Time) *Builder {
+	b.args.Constraints.CreatedEnd = t.Unix()
+	return b
+}
+
+// ModifiedAfter limits the results to revisions modified at or after T
+func (b *Builder) ModifiedAfter(t time.Time) *Builder {
+	b.args.Constraints.ModifiedStart = t.Unix()
+	return b
+}
+
+// ModifiedBefore limits the results to revisions modified at or before T
+func (b *Builder) ModifiedBefore(t time.Time) *Builder {
+	b.args.Constraints.ModifiedEnd = t.Unix()
+	return b
+}
+
+// Order sets the result order, e.g. updated or relevance
+func (b *Builder) Order(order string) *Builder {
+	b.args.Order = order
+	return b
+}
+
+// WithReviewers attaches the reviewers of the revisions
+func (b *Builder) WithReviewers() *Builder {
+	b.args.Attachments.Reviewers = true
+	return b
+}
+
+// WithSubscribers attaches the subscribers of the revisions
+func (b *Builder) WithSubscribers() *Builder {
+	b.args.Attachments.Subscribers = true
+	return b
+}
+
+// WithProjects attaches the projects the revisions are tagged with
+func (b *Builder) WithProjects() *Builder {
+	b.args.Attachments.Projects = true
+	return b
+}
+
+// DiffBuilder builds DiffSearchArgs
+type DiffBuilder struct {
+	args phabTypes.DiffSearchArgs
+}
+
+// Diffs starts a new, unconstrained diff query
+func Diffs() *DiffBuilder {
+	return &DiffBuilder{}
+}
+
+// Args returns the built arguments
+func (b *DiffBuilder) Args() phabTypes.DiffSearchArgs {
+	return b.args
+}
+
+// IDs limits the results to diffs with IDS
+func (b *DiffBuilder) IDs(ids ...int) *DiffBuilder {
+	b.args.Constraints.IDs = append(b.args.Constraints.IDs, ids...)
+	return b
+}
+
+// PHIDs limits the results to diffs with PHIDS
+func (b *DiffBuilder) PHIDs(phids ...string) *DiffBuilder {
+	b.args.Constraints.PHIDs = append(b.args.Constraints.PHIDs, phids...)
+	return b
+}
+
+// OfRevisions limits the results to diffs of the revisions with PHIDS
+func (b *DiffBuilder) OfRevisions(phids ...string) *DiffBuilder {
+	b.args.Constraints.RevisionPHIDs = append(b.args.Constraints.RevisionPHIDs, phids...)
+	return b
+}
+
+// Order sets the result order, e.g. newest or oldest
+func (b *DiffBuilder) Order(order string) *DiffBuilder {
+	b.args.Order = order
+	return b
+}
+
+// WithCommits attaches the local commits of the diffs
+func (b *DiffBuilder) WithCommits() *DiffBuilder {
+	b.args.Attachments.Commits = true
+	return b
+}
@@ -0,0 +1,34 @@
+package differential
+
+import (
+	"testing"
+
+	query "github.com/google/go-querystring/query"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func TestQuery(t *testing.T) {
+	args := Query().
+		Status(phabTypes.RevisionStatusNeedsReview, phabTypes.RevisionStatusAccepted).
+		ReviewedBy("PHID-USER-me").
+		WithReviewers().
+		Args()
+	values, err := query.Values(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses := values["constraints[statuses][]"]; len(statuses) != 2 || statuses[1] != "accepted" {
+		t.Errorf("Unexpected statuses %v", statuses)
+	}
+	if values.Get("constraints[reviewerPHIDs][]") != "PHID-USER-me" || values.Get("attachments[reviewers]") != "true" {
+		t.Errorf("Unexpected arguments %v", values)
+	}
+}
+
+func TestDiffs(t *testing.T) {
+	args := Diffs().OfRevisions("PHID-DREV-1").WithCommits().Args()
+	if len(args.Constraints.RevisionPHIDs) != 1 || !args.Attachments.Commits {
+		t.Errorf("Unexpected arguments %+v", args)
+	}
+}
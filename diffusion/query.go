@@ -0,0 +1,106 @@
+// Package diffusion builds arguments for diffusion.repository.search
+//
+//	args := diffusion.Query().Callsigns("XYZ").WithURIs().Args()
+//	results := phab.CallSearch(ctx, diffusion.Endpoint, args, phabTypes.Repository{})
+package diffusion
+
+import (
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// Endpoint the built arguments are meant for
+const Endpoint = "diffusion.repository.search"
+
+// Builder builds RepositorySearchArgs. Every method modifies the builder
+// and returns it, so calls can be chained.
+type Builder struct {
+	args phabTypes.RepositorySearchArgs
+}
+
+// Query starts a new, unconstrained repository query
+func Query() *Builder {
+	return &Builder{}
+}
+
+// Args returns the built arguments
+func (b *Builder) Args() phabTypes.RepositorySearchArgs {
+	return b.args
+}
+
+// QueryKey starts from a saved or builtin query, e.g. active
+func (b *Builder) QueryKey(key string) *Builder {
+	b.args.QueryKey = key
+	return b
+}
+
+// IDs limits the results to repositories with IDS
+func (b *Builder) IDs(ids ...int) *Builder {
+	b.args.Constraints.Ids = append(b.args.Constraints.Ids, ids...)
+	return b
+}
+
+// PHIDs limits the results to repositories with PHIDS
+func (b *Builder) PHIDs(phids ...string) *Builder {
+	b.args.Constraints.Phids = append(b.args.Constraints.Phids, phids...)
+	return b
+}
+
+// Callsigns limits the results to repositories with any of CALLSIGNS, e.g. XYZ for rXYZ
+func (b *Builder) Callsigns(callsigns ...string) *Builder {
+	b.args.Constraints.Callsigns = append(b.args.Constraints.Callsigns, callsigns...)
+	return b
+}
+
+// ShortNames limits the results to repositories with any of NAMES
+func (b *Builder) ShortNames(names ...string) *Builder {
+	b.args.Constraints.ShortNames = append(b.args.Constraints.ShortNames, names...)
+	return b
+}
+
+// VCS limits the results to repositories of any of TYPES: git, hg or svn
+func (b *Builder) VCS(types ...string) *Builder {
+	b.args.Constraints.Types = append(b.args.Constraints.Types, types...)
+	return b
+}
+
+// URIs limits the results to repositories with any of URIS
+func (b *Builder) URIs(uris ...string) *Builder {
+	b.args.Constraints.Uris = append(b.args.Constraints.Uris, uris...)
+	return b
+}
+
+// InProjects limits the results to repositories tagged with all of the projects with PHIDS
+func (b *Builder) InProjects(phids ...string) *Builder {
+	b.args.Constraints.Projects = append(b.args.Constraints.Projects, phids...)
+	return b
+}
+
+// InSpaces limits the results to repositories in any of the spaces with PHIDS
+func (b *Builder) InSpaces(phids ...string) *Builder {
+	b.args.Constraints.Spaces = append(b.args.Constraints.Spaces, phids...)
+	return b
+}
+
+// Matching limits the results to repositories matching the full-text query TEXT
+func (b *Builder) Matching(text string) *Builder {
+	b.args.Constraints.Query = text
+	return b
+}
+
+// Order sets the result order, e.g. name or callsign
+func (b *Builder) Order(order string) *Builder {
+	b.args.Order = order
+	return b
+}
+
+// WithURIs attaches the clone URIs of the repositories
+func (b *Builder) WithURIs() *Builder {
+	b.args.Attachments.Uris = true
+	return b
+}
+
+// WithProjects attaches the projects the repositories are tagged with
+func (b *Builder) WithProjects() *Builder {
+	b.args.Attachments.Projects = true
+	return b
+}
@@ -0,0 +1,41 @@
+package diffusion
+
+import (
+	"testing"
+
+	query "github.com/google/go-querystring/query"
+)
+
+func TestQuery(t *testing.T) {
+	args := Query().
+		Callsigns("XYZ", "ABC").
+		VCS("git").
+		Matching("infrastructure").
+		Order("callsign").
+		WithURIs().
+		Args()
+
+	values, err := query.Values(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"constraints[types][]": "git",
+		"constraints[query]":   "infrastructure",
+		"order":                "callsign",
+		"attachments[uris]":    "true",
+	} {
+		if value := values.Get(key); value != expected {
+			t.Errorf("%s: expected %q, got %q", key, expected, value)
+		}
+	}
+	if callsigns := values["constraints[callsigns][]"]; len(callsigns) != 2 || callsigns[0] != "XYZ" {
+		t.Errorf("Expected callsigns XYZ and ABC, got %v", callsigns)
+	}
+	if _, set := values["attachments[projects]"]; set {
+		t.Error("Projects attached without being asked for")
+	}
+	if _, set := values["constraints[shortNames][]"]; set {
+		t.Error("Short names constrained without being asked for")
+	}
+}
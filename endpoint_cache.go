@@ -0,0 +1,104 @@
+package phabricator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// How long a cached conduit.query response is used if PhabOptions.CacheTTL is not set
+	defaultCacheTTL = 24 * time.Hour
+)
+
+// endpointCache is the on-disk representation of a conduit.query response
+type endpointCache struct {
+	API       string                  `json:"api"`
+	Fetched   time.Time               `json:"fetched"`
+	Endpoints map[string]endpointInfo `json:"endpoints"`
+}
+
+// The cache file name is derived from the API root, so that several
+// Phabricator instances can share one cache directory
+func endpointCachePath(dir, api string) string {
+	sum := sha256.Sum256([]byte(api))
+	return filepath.Join(dir, "conduit-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// readEndpointCache returns the cached endpoints for API, if there is a cache
+// entry younger than TTL. Any failure is treated as a cache miss.
+func readEndpointCache(dir, api string, ttl time.Duration) (map[string]endpointInfo, bool) {
+	cachePath := endpointCachePath(dir, api)
+	logger := logger.WithFields(log.Fields{
+		"api":  api,
+		"path": cachePath,
+	})
+	data, err := ioutil.ReadFile(cachePath)
+	if err != nil {
+		logger.WithError(err).Debug("Endpoint cache miss")
+		return nil, false
+	}
+	var cache endpointCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		logger.WithError(err).Warn("Ignoring corrupted endpoint cache")
+		return nil, false
+	}
+	if cache.API != api {
+		logger.WithField("cached_api", cache.API).Warn("Endpoint cache belongs to a different API")
+		return nil, false
+	}
+	if time.Since(cache.Fetched) > ttl {
+		logger.WithField("fetched", cache.Fetched).Debug("Endpoint cache expired")
+		return nil, false
+	}
+	logger.Debug("Using cached endpoints")
+	return cache.Endpoints, true
+}
+
+// writeEndpointCache stores ENDPOINTS for API. The file is written
+// to a temporary location first, so concurrent readers never see
+// a partially written cache.
+func writeEndpointCache(dir, api string, endpoints map[string]endpointInfo) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(endpointCache{
+		API:       api,
+		Fetched:   time.Now(),
+		Endpoints: endpoints,
+	})
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, ".conduit-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), endpointCachePath(dir, api))
+}
+
+// staticEndpoints builds the endpoint list from names only. Endpoints
+// registered this way carry no parameter info, so they are never validated.
+func staticEndpoints(names []string) map[string]endpointInfo {
+	endpoints := make(map[string]endpointInfo, len(names))
+	for _, name := range names {
+		endpoints[name] = endpointInfo{}
+	}
+	return endpoints
+}
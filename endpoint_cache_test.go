@@ -0,0 +1,55 @@
+package phabricator
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEndpointCacheRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phabricator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	api := "https://phab.example.com/api/"
+	endpoints := map[string]endpointInfo{"maniphest.search": maniphestSearchInfo}
+	if err := writeEndpointCache(dir, api, endpoints); err != nil {
+		t.Fatal(err)
+	}
+	cached, hit := readEndpointCache(dir, api, time.Hour)
+	if !hit {
+		t.Fatal("Freshly written cache not found")
+	}
+	if len(cached["maniphest.search"].Params) != len(maniphestSearchInfo.Params) {
+		t.Error("Cached endpoint params differ from the original")
+	}
+	if _, hit := readEndpointCache(dir, "https://other.example.com/api/", time.Hour); hit {
+		t.Error("Cache of one API used for another")
+	}
+	if _, hit := readEndpointCache(dir, api, -time.Second); hit {
+		t.Error("Expired cache used")
+	}
+}
+
+func TestPhabStaticEndpoints(t *testing.T) {
+	var phab Phabricator
+	// No server is listening here, so Init must not try to discover anything
+	err := phab.Init(&PhabOptions{
+		API:       "http://127.0.0.1:1/api/",
+		Token:     "api-token",
+		LogLevel:  "error",
+		Endpoints: []string{"maniphest.search", "maniphest.edit"},
+	})
+	if err != nil {
+		t.Fatalf("Init with static endpoints failed: %s", err)
+	}
+	if _, found := phab.searchEndpoints["maniphest.search"]; !found {
+		t.Error("Static search endpoint not registered")
+	}
+	if _, found := phab.editEndpoints["maniphest.edit"]; !found {
+		t.Error("Static edit endpoint not registered")
+	}
+}
@@ -0,0 +1,177 @@
+package phabricator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// TicketEnums holds the task statuses and priorities configured on an
+// instance, which may differ from the stock ones in the types package
+type TicketEnums struct {
+	Statuses   []phabTypes.TicketStatusInfo
+	Priorities []phabTypes.TicketPriorityInfo
+}
+
+// Status returns the status with VALUE
+func (e *TicketEnums) Status(value phabTypes.TicketStatus) (phabTypes.TicketStatusInfo, bool) {
+	for _, status := range e.Statuses {
+		if status.Value == value {
+			return status, true
+		}
+	}
+	return phabTypes.TicketStatusInfo{}, false
+}
+
+// Priority returns the priority with VALUE
+func (e *TicketEnums) Priority(value phabTypes.TicketPriority) (phabTypes.TicketPriorityInfo, bool) {
+	for _, priority := range e.Priorities {
+		if priority.Value == value {
+			return priority, true
+		}
+	}
+	return phabTypes.TicketPriorityInfo{}, false
+}
+
+// PriorityByKeyword returns the priority with KEYWORD, e.g. high,
+// as used by maniphest.edit. Names are matched too, case-insensitively.
+func (e *TicketEnums) PriorityByKeyword(keyword string) (phabTypes.TicketPriorityInfo, bool) {
+	for _, priority := range e.Priorities {
+		for _, known := range priority.Keywords {
+			if strings.EqualFold(known, keyword) {
+				return priority, true
+			}
+		}
+		if strings.EqualFold(priority.Name, keyword) {
+			return priority, true
+		}
+	}
+	return phabTypes.TicketPriorityInfo{}, false
+}
+
+// TicketStatuses returns the task statuses using maniphest.status.search
+func (p *Phabricator) TicketStatuses(ctx context.Context) ([]phabTypes.TicketStatusInfo, error) {
+	var resp struct {
+		Data []phabTypes.TicketStatusInfo `json:"data"`
+	}
+	if err := p.call(ctx, "maniphest.status.search", "", &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// TicketPriorities returns the task priorities using maniphest.priority.search
+func (p *Phabricator) TicketPriorities(ctx context.Context) ([]phabTypes.TicketPriorityInfo, error) {
+	var resp struct {
+		Data []phabTypes.TicketPriorityInfo `json:"data"`
+	}
+	if err := p.call(ctx, "maniphest.priority.search", "", &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// TicketEnums loads task statuses and priorities of the instance.
+// The result is cached for the lifetime of the instance.
+func (p *Phabricator) TicketEnums(ctx context.Context) (*TicketEnums, error) {
+	p.ticketEnumsLock.Lock()
+	defer p.ticketEnumsLock.Unlock()
+	if p.ticketEnums != nil {
+		return p.ticketEnums, nil
+	}
+	statuses, err := p.TicketStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	priorities, err := p.TicketPriorities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logger.WithFields(log.Fields{
+		"statuses":   len(statuses),
+		"priorities": len(priorities),
+	}).Debug("Loaded task statuses and priorities")
+	p.ticketEnums = &TicketEnums{Statuses: statuses, Priorities: priorities}
+	return p.ticketEnums, nil
+}
+
+// validateEnums checks statuses and priorities in VALUES of a call to
+// ENDPOINT. Task values are checked against the instance, loading them
+// on first use, revision statuses against the fixed list.
+func (p *Phabricator) validateEnums(ctx context.Context, endpoint string, values url.Values) error {
+	var problems []string
+	switch endpoint {
+	case "maniphest.search":
+		if len(values["constraints[statuses][]"]) == 0 && len(values["constraints[priorities][]"]) == 0 {
+			return nil
+		}
+		enums, err := p.TicketEnums(ctx)
+		if err != nil {
+			return err
+		}
+		for _, value := range values["constraints[statuses][]"] {
+			status := phabTypes.TicketStatus(value)
+			if _, known := enums.Status(status); !known && status != phabTypes.TicketStatusAnyOpen && status != phabTypes.TicketStatusAnyClosed {
+				problems = append(problems, fmt.Sprintf("unknown task status %q", value))
+			}
+		}
+		for _, value := range values["constraints[priorities][]"] {
+			priority, err := strconv.Atoi(value)
+			if _, known := enums.Priority(phabTypes.TicketPriority(priority)); err != nil || !known {
+				problems = append(problems, fmt.Sprintf("unknown task priority %q", value))
+			}
+		}
+	case "maniphest.edit":
+		var enums *TicketEnums
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !strings.HasSuffix(key, "[type]") {
+				continue
+			}
+			typ := values.Get(key)
+			if typ != "status" && typ != "priority" {
+				continue
+			}
+			if enums == nil {
+				var err error
+				if enums, err = p.TicketEnums(ctx); err != nil {
+					return err
+				}
+			}
+			value := values.Get(strings.TrimSuffix(key, "[type]") + "[value]")
+			if _, known := enums.Status(phabTypes.TicketStatus(value)); typ == "status" && !known {
+				problems = append(problems, fmt.Sprintf("unknown task status %q", value))
+			}
+			if _, known := enums.PriorityByKeyword(value); typ == "priority" && !known {
+				problems = append(problems, fmt.Sprintf("unknown task priority keyword %q", value))
+			}
+		}
+	case "differential.revision.search":
+		for _, value := range values["constraints[statuses][]"] {
+			if !phabTypes.RevisionStatus(value).Valid() {
+				problems = append(problems, fmt.Sprintf("unknown revision status %q", value))
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	logger.WithFields(log.Fields{
+		"endpoint": endpoint,
+		"problems": problems,
+	}).Error("Arguments contain unknown statuses or priorities")
+	return &ValidationError{Endpoint: endpoint, Problems: problems}
+}
@@ -0,0 +1,92 @@
+package phabricator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func enumServer(requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		switch r.URL.Path {
+		case "/api/maniphest.status.search":
+			fmt.Fprint(w, `{"result": {"data": [
+				{"name": "Open", "value": "open", "closed": false, "special": "default"},
+				{"name": "In Review", "value": "review", "closed": false},
+				{"name": "Resolved", "value": "resolved", "closed": true, "special": "closed"}
+			]}}`)
+		case "/api/maniphest.priority.search":
+			fmt.Fprint(w, `{"result": {"data": [
+				{"name": "Unbreak Now!", "short": "Unbreak!", "color": "pink", "keywords": ["unbreak"], "value": 100},
+				{"name": "High", "short": "High", "color": "red", "keywords": ["high"], "value": 80}
+			]}}`)
+		default:
+			fmt.Fprint(w, `{"error_code": "ERR-CONDUIT-CORE", "error_info": "Unknown method"}`)
+		}
+	}))
+}
+
+func TestValidateTicketEnums(t *testing.T) {
+	var requests int32
+	server := enumServer(&requests)
+	defer server.Close()
+
+	ctx := context.Background()
+	phab, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-token"), WithLogLevel("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	phab.apiInfo = map[string]endpointInfo{
+		"maniphest.search": maniphestSearchInfo,
+		"maniphest.edit":   maniphestEditInfo,
+	}
+
+	args := phabTypes.TicketSearchArgs{}
+	args.Constraints.Statuses = []phabTypes.TicketStatus{"review", phabTypes.TicketStatusAnyClosed}
+	args.Constraints.Priorities = []phabTypes.TicketPriority{phabTypes.TicketPriorityHigh}
+	if err := phab.Validate(ctx, "maniphest.search", args); err != nil {
+		t.Errorf("Custom status rejected: %s", err)
+	}
+
+	args.Constraints.Statuses = []phabTypes.TicketStatus{"reslved"}
+	args.Constraints.Priorities = []phabTypes.TicketPriority{phabTypes.TicketPriorityNormal}
+	err = phab.Validate(ctx, "maniphest.search", args)
+	verr, ok := err.(*ValidationError)
+	if !ok || len(verr.Problems) != 2 {
+		t.Errorf("Expected a typo and an unknown priority, got %v", err)
+	}
+
+	edit := &EditArguments{
+		ObjectIdentifier: "T1",
+		Transactions: []PhabTransaction{
+			NewTransaction("status", "resolved"),
+			NewTransaction("priority", "unbreak"),
+		},
+	}
+	if err := phab.Validate(ctx, "maniphest.edit", edit); err != nil {
+		t.Errorf("Valid edit rejected: %s", err)
+	}
+	edit.Transactions[1] = NewTransaction("priority", "urgent")
+	if err := phab.Validate(ctx, "maniphest.edit", edit); err == nil {
+		t.Error("Unknown priority keyword accepted")
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Statuses and priorities loaded %d times", n)
+	}
+}
+
+func TestValidateRevisionStatuses(t *testing.T) {
+	phab := Phabricator{apiInfo: map[string]endpointInfo{"differential.revision.search": maniphestSearchInfo}}
+	args := phabTypes.RevisionSearchArgs{}
+	args.Constraints.Statuses = []phabTypes.RevisionStatus{phabTypes.RevisionStatusNeedsReview, "accpeted"}
+	if err := phab.Validate(context.Background(), "differential.revision.search", args); err == nil {
+		t.Error("Misspelled revision status accepted")
+	}
+}
@@ -15,11 +15,14 @@ This short program prints the names and ids of all the tickets created by the
 current use in the past week.
 */
 func main() {
-	var phab phabricator.Phabricator
-	err := phab.Init(&phabricator.PhabOptions{
-		LogLevel: "error", // Must be a level recognized by the logrus library
-		Timeout:  10 * time.Second,
-	})
+	// The context allows you to cancel the current call prematurely
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	phab, err := phabricator.New(ctx,
+		phabricator.WithLogLevel("error"), // Must be a level recognized by the logrus library
+		phabricator.WithTimeout(10*time.Second),
+	)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -34,9 +37,6 @@ func main() {
 	thirtyDaysAgo := time.Now().UTC().AddDate(0, 0, -30).Unix()
 	ticketArgs.Constraints.CreatedStart = thirtyDaysAgo
 
-	// The context allows you to cancel the current call prematurely
-	ctx, cancelCtx := context.WithCancel(context.Background())
-	defer cancelCtx()
 	results := phab.CallSearch(ctx, "maniphest.search", ticketArgs, phabTypes.Ticket{})
 	if results == nil {
 		log.Fatal("Non-existent endpoint")
@@ -0,0 +1,247 @@
+// Package export archives all Maniphest tasks into NDJSON or CSV files.
+// The pagination cursor is checkpointed to disk after every page, so an
+// interrupted export resumes where it stopped, and later exports can be
+// incremental, only appending tasks modified since the last one.
+//
+//	exporter := export.New(phab, "tasks.ndjson")
+//	exporter.Incremental = true
+//	n, err := exporter.Run(ctx)
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.showmax.cc/phabricator"
+	"go.showmax.cc/phabricator/format"
+	"go.showmax.cc/phabricator/maniphest"
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// Checkpoint is the state of exports saved between pages and runs
+type Checkpoint struct {
+	// Cursor of the next page of an unfinished export
+	After string `json:"after,omitempty"`
+	// ModifiedStart constraint of the unfinished export
+	ModifiedStart int64 `json:"modifiedStart,omitempty"`
+	// Start of the unfinished export, as a UNIX timestamp
+	Started int64 `json:"started,omitempty"`
+	// Size of the output file covering the pages exported so far
+	Offset int64 `json:"offset"`
+	// Number of tasks written by the unfinished or last export
+	Exported int `json:"exported"`
+	// Start of the last finished export. Incremental exports
+	// include tasks modified since then.
+	Completed int64 `json:"completed,omitempty"`
+}
+
+// Running tells if an export was interrupted and can be resumed
+func (c *Checkpoint) Running() bool {
+	return c.After != ""
+}
+
+// ReadCheckpoint reads the checkpoint at PATH. A missing file is
+// an empty checkpoint.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &checkpoint, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("Corrupted checkpoint %s: %v", path, err)
+	}
+	return &checkpoint, nil
+}
+
+// Save writes the checkpoint to PATH atomically, an interrupted
+// write leaves the previous checkpoint in place
+func (c *Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Exporter writes tasks from maniphest.search to Output
+type Exporter struct {
+	Searcher phabricator.Searcher
+	// Path of the exported file
+	Output string
+	// Path of the checkpoint, Output with a .checkpoint suffix by default
+	Checkpoint string
+	// ndjson or csv
+	Format string
+	// CSV columns, the most common task fields by default
+	Columns []format.Column
+	// Base search arguments, e.g. to export a single project.
+	// All attachments are always requested.
+	Args phabTypes.TicketSearchArgs
+	// Only export tasks modified since the last finished export,
+	// appending them to Output. Consumers should keep the last
+	// record of every task.
+	Incremental bool
+	// Now is the start time of exports, time.Now if nil
+	Now func() time.Time
+}
+
+// New creates an Exporter of all tasks to the NDJSON file OUTPUT
+func New(searcher phabricator.Searcher, output string) *Exporter {
+	return &Exporter{Searcher: searcher, Output: output, Format: "ndjson"}
+}
+
+func (e *Exporter) checkpointPath() string {
+	if e.Checkpoint != "" {
+		return e.Checkpoint
+	}
+	return e.Output + ".checkpoint"
+}
+
+func (e *Exporter) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
+
+// Run resumes the interrupted export, or starts a new one. A new
+// full export replaces Output, an incremental one appends to it.
+// It returns the number of tasks written by this run.
+func (e *Exporter) Run(ctx context.Context) (int, error) {
+	if e.Format != "" && e.Format != "ndjson" && e.Format != "csv" {
+		return 0, fmt.Errorf("Unsupported export format %q, expected ndjson or csv", e.Format)
+	}
+	path := e.checkpointPath()
+	checkpoint, err := ReadCheckpoint(path)
+	if err != nil {
+		return 0, err
+	}
+	if !checkpoint.Running() {
+		checkpoint.Started = e.now().Unix()
+		checkpoint.Exported = 0
+		checkpoint.ModifiedStart = 0
+		if e.Incremental && checkpoint.Completed > 0 {
+			checkpoint.ModifiedStart = checkpoint.Completed
+		} else {
+			checkpoint.Offset = 0
+		}
+	}
+
+	out, err := os.OpenFile(e.Output, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	info, err := out.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() < checkpoint.Offset {
+		// Truncate would pad the file with zeros
+		return 0, fmt.Errorf("%s is shorter than checkpoint %s says, remove the checkpoint to export all tasks again",
+			e.Output, path)
+	}
+	// Drop anything written after the last checkpoint
+	if err := out.Truncate(checkpoint.Offset); err != nil {
+		return 0, err
+	}
+	if _, err := out.Seek(checkpoint.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	writer := e.writer(out, checkpoint.Offset > 0)
+
+	args := e.Args
+	args.Attachments.Columns = true
+	args.Attachments.Subscribers = true
+	args.Attachments.Projects = true
+	if checkpoint.ModifiedStart > 0 {
+		args.Constraints.ModifiedStart = checkpoint.ModifiedStart
+	}
+
+	exported := 0
+	for {
+		page, err := e.Searcher.SearchPage(ctx, maniphest.Endpoint, args, phabTypes.Ticket{}, checkpoint.After)
+		if err != nil {
+			return exported, fmt.Errorf("Export interrupted after %d tasks: %w", checkpoint.Exported, err)
+		}
+		for _, ticket := range page.Results {
+			if err := writer.Write(ticket); err != nil {
+				return exported, err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return exported, err
+		}
+		if err := out.Sync(); err != nil {
+			return exported, err
+		}
+		offset, err := out.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return exported, err
+		}
+		exported += len(page.Results)
+		checkpoint.Exported += len(page.Results)
+		checkpoint.Offset = offset
+		checkpoint.After = page.After
+		if page.After == "" {
+			checkpoint.Completed = checkpoint.Started
+			checkpoint.Started = 0
+			checkpoint.ModifiedStart = 0
+		}
+		if err := checkpoint.Save(path); err != nil {
+			return exported, err
+		}
+		if page.After == "" {
+			return exported, nil
+		}
+	}
+}
+
+// defaultColumns of CSV exports
+var defaultColumns = format.ParseColumns("id,phid,fields.name,fields.status.value,fields.priority.value," +
+	"fields.authorPHID,fields.ownerPHID,fields.dateCreated,fields.dateModified,fields.dateClosed," +
+	"attachments.projects.projectPHIDs,attachments.subscribers.subscriberPHIDs")
+
+// writer creates the format.Writer of OUT. APPEND is set when
+// continuing a file that already has a CSV header.
+func (e *Exporter) writer(out io.Writer, append bool) format.Writer {
+	if e.Format != "csv" {
+		return format.NewJSONLines(out)
+	}
+	columns := e.Columns
+	if columns == nil {
+		columns = defaultColumns
+	}
+	writer := format.NewCSV(out, columns...)
+	if append {
+		writer.OmitHeader()
+	}
+	return writer
+}
@@ -0,0 +1,171 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.showmax.cc/phabricator"
+	"go.showmax.cc/phabricator/phabmock"
+	"go.showmax.cc/phabricator/phabtest"
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func addTicket(t *testing.T, server *phabtest.Server, name string, modified int64) {
+	t.Helper()
+	var ticket phabTypes.Ticket
+	ticket.Fields.Name = name
+	ticket.Fields.DateModified = modified
+	if err := server.Add("maniphest.search", ticket); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exportedIDs reads the task IDs from the NDJSON file at PATH
+func exportedIDs(t *testing.T, path string) []int {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var ids []int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var ticket phabTypes.Ticket
+		if err := json.Unmarshal(scanner.Bytes(), &ticket); err != nil {
+			t.Fatalf("Invalid line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, ticket.Id)
+	}
+	return ids
+}
+
+func TestResumeAndIncremental(t *testing.T) {
+	server := phabtest.NewServer()
+	defer server.Close()
+	server.PageSize = 2
+	for i, name := range []string{"one", "two", "three", "four", "five"} {
+		addTicket(t, server, name, int64(100+i))
+	}
+	ctx := context.Background()
+	phab, err := phabricator.New(ctx, phabricator.WithAPI(server.API()), phabricator.WithToken("api-test"),
+		phabricator.WithLogLevel("panic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Crash on the second page
+	pages := 0
+	crashing := &phabmock.ClientMock{
+		SearchPageFunc: func(ctx context.Context, endpoint string, args phabricator.EndpointArguments, typ interface{}, after string) (*phabricator.ResultPage, error) {
+			if pages++; pages == 2 {
+				return nil, errors.New("Connection reset")
+			}
+			return phab.SearchPage(ctx, endpoint, args, typ, after)
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "tasks.ndjson")
+	exporter := New(crashing, output)
+	exporter.Incremental = true
+	exporter.Now = func() time.Time { return time.Unix(200, 0) }
+
+	if n, err := exporter.Run(ctx); err == nil || n != 2 {
+		t.Fatalf("Expected a crash after 2 tasks, got %d, %v", n, err)
+	}
+	checkpoint, err := ReadCheckpoint(output + ".checkpoint")
+	if err != nil || !checkpoint.Running() || checkpoint.Exported != 2 {
+		t.Fatalf("Unexpected checkpoint %+v, %v", checkpoint, err)
+	}
+	// Garbage written after the checkpoint is discarded
+	file, _ := os.OpenFile(output, os.O_APPEND|os.O_WRONLY, 0)
+	file.WriteString(`{"id": 99, "trunc`)
+	file.Close()
+
+	if n, err := exporter.Run(ctx); err != nil || n != 3 {
+		t.Fatalf("Resumed export wrote %d tasks, %v", n, err)
+	}
+	if ids := exportedIDs(t, output); len(ids) != 5 || ids[0] != 5 || ids[4] != 1 {
+		t.Errorf("Unexpected export %v", ids)
+	}
+	args := crashing.SearchPageCalls()[0].Arguments.(phabTypes.TicketSearchArgs)
+	if !args.Attachments.Projects || !args.Attachments.Subscribers || !args.Attachments.Columns {
+		t.Errorf("Attachments not requested: %+v", args.Attachments)
+	}
+
+	// Only tasks modified since the first export are appended
+	addTicket(t, server, "six", 250)
+	if n, err := exporter.Run(ctx); err != nil || n != 1 {
+		t.Fatalf("Incremental export wrote %d tasks, %v", n, err)
+	}
+	if ids := exportedIDs(t, output); len(ids) != 6 || ids[5] != 6 {
+		t.Errorf("Unexpected incremental export %v", ids)
+	}
+	last := crashing.SearchPageCalls()[len(crashing.SearchPageCalls())-1]
+	if since := last.Arguments.(phabTypes.TicketSearchArgs).Constraints.ModifiedStart; since != 200 {
+		t.Errorf("Incremental export modified since %d", since)
+	}
+
+	// An output shorter than the checkpoint can't be appended to
+	if err := os.Truncate(output, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exporter.Run(ctx); err == nil {
+		t.Error("Export appended to a truncated output")
+	}
+	if info, _ := os.Stat(output); info.Size() != 10 {
+		t.Errorf("Failed export changed the output to %d bytes", info.Size())
+	}
+}
+
+func TestCSV(t *testing.T) {
+	server := phabtest.NewServer()
+	defer server.Close()
+	server.PageSize = 1
+	addTicket(t, server, "one", 1)
+	addTicket(t, server, "two, three", 2)
+	ctx := context.Background()
+	phab, err := phabricator.New(ctx, phabricator.WithAPI(server.API()), phabricator.WithToken("api-test"),
+		phabricator.WithLogLevel("panic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exporter := New(phab, filepath.Join(dir, "tasks.csv"))
+	exporter.Format = "csv"
+	exporter.Checkpoint = filepath.Join(dir, "state.json")
+	if _, err := exporter.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(exporter.Output)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "id,phid,fields.name,") || !strings.HasPrefix(lines[1], `2,PHID-TASK-`) ||
+		!strings.Contains(lines[1], `"two, three"`) {
+		t.Errorf("Unexpected CSV:\n%s", data)
+	}
+
+	exporter.Format = "xml"
+	if _, err := exporter.Run(ctx); err == nil {
+		t.Error("Expected an unsupported format error")
+	}
+	if after, _ := ioutil.ReadFile(exporter.Output); string(after) != string(data) {
+		t.Error("Failed export touched the output")
+	}
+}
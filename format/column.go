@@ -0,0 +1,152 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Column of table and CSV output. The value is either taken from Path,
+// or computed by Value if it's set.
+type Column struct {
+	Header string
+	// Dot-separated path of JSON keys, e.g. fields.status.name
+	Path  string
+	Value func(object interface{}) string
+}
+
+// Text returns the value of the column for OBJECT
+func (c Column) Text(object interface{}) string {
+	if c.Value != nil {
+		return c.Value(object)
+	}
+	value, err := Lookup(object, c.Path)
+	if err != nil {
+		return ""
+	}
+	return Text(value)
+}
+
+// ParseColumns parses a comma-separated list of dot-paths,
+// e.g. id,fields.status.name. Each path is also its column's header.
+func ParseColumns(list string) []Column {
+	var columns []Column
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path != "" {
+			columns = append(columns, Column{Header: path, Path: path})
+		}
+	}
+	return columns
+}
+
+// DefaultColumns are used when no columns are given. Objects with
+// a String method, like Ticket and Project, are printed with it,
+// other objects by their ID and PHID.
+func DefaultColumns(object interface{}) []Column {
+	if _, isStringer := object.(fmt.Stringer); isStringer {
+		return []Column{{Header: "object", Value: Text}}
+	}
+	return []Column{{Header: "id", Path: "id"}, {Header: "phid", Path: "phid"}}
+}
+
+// Lookup finds PATH in OBJECT. Each dot-separated element of the path
+// is a JSON key of a struct field (or its Go name), a map key or a list index.
+func Lookup(object interface{}, path string) (interface{}, error) {
+	value := reflect.ValueOf(object)
+	if path == "" {
+		return object, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return nil, nil
+			}
+			value = value.Elem()
+		}
+		switch value.Kind() {
+		case reflect.Struct:
+			field, found := structField(value, key)
+			if !found {
+				return nil, fmt.Errorf("No field %q in %s", key, value.Type())
+			}
+			value = field
+		case reflect.Map:
+			if value.Type().Key().Kind() != reflect.String {
+				return nil, fmt.Errorf("Map keys of %s are not strings", value.Type())
+			}
+			value = value.MapIndex(reflect.ValueOf(key).Convert(value.Type().Key()))
+			if !value.IsValid() {
+				return nil, nil
+			}
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("Invalid list index %q", key)
+			}
+			if index >= value.Len() {
+				return nil, nil
+			}
+			value = value.Index(index)
+		default:
+			return nil, fmt.Errorf("Cannot look up %q in %s", key, value.Type())
+		}
+	}
+	return value.Interface(), nil
+}
+
+// structField finds the field of VALUE with JSON key or name KEY
+func structField(value reflect.Value, key string) (reflect.Value, bool) {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == key || (name == "" && strings.EqualFold(field.Name, key)) || field.Name == key {
+			return value.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Text renders VALUE as a single table or CSV cell: lists of strings
+// comma-separated, other lists, maps and structs as JSON
+func Text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case []string:
+		return strings.Join(v, ",")
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Struct:
+		// Values of types with a pointer String method, e.g. Ticket
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		if stringer, isStringer := ptr.Interface().(fmt.Stringer); isStringer {
+			return stringer.String()
+		}
+		fallthrough
+	case reflect.Slice, reflect.Array, reflect.Map:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(encoded)
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return ""
+		}
+		return Text(rv.Elem().Interface())
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,69 @@
+package format
+
+import (
+	"testing"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func TestLookup(t *testing.T) {
+	var ticket phabTypes.Ticket
+	ticket.Id = 7
+	ticket.Fields.Status.Name = "Open"
+	ticket.Attachments.Projects.ProjectPHIDs = []string{"PHID-PROJ-1", "PHID-PROJ-2"}
+	ticket.Attachments.Columns.Boards = map[string]phabTypes.TicketAttachmentBoard{
+		"PHID-PROJ-1": {Columns: []phabTypes.TicketAttachmentColumn{{Name: "Backlog"}}},
+	}
+
+	tests := []struct {
+		path string
+		text string
+	}{
+		{"id", "7"},
+		{"Id", "7"},
+		{"fields.status.name", "Open"},
+		{"attachments.projects.projectPHIDs", "PHID-PROJ-1,PHID-PROJ-2"},
+		{"attachments.projects.projectPHIDs.1", "PHID-PROJ-2"},
+		{"attachments.projects.projectPHIDs.5", ""},
+		{"attachments.columns.boards.PHID-PROJ-1.columns.0.name", "Backlog"},
+		{"attachments.columns.boards.PHID-PROJ-9.columns", ""},
+		{"", "T7: "},
+	}
+	for _, test := range tests {
+		value, err := Lookup(&ticket, test.path)
+		if err != nil {
+			t.Errorf("%s: %v", test.path, err)
+			continue
+		}
+		if got := Text(value); got != test.text {
+			t.Errorf("%s: expected %q, got %q", test.path, test.text, got)
+		}
+	}
+
+	for _, path := range []string{"fields.nope", "id.x", "attachments.projects.projectPHIDs.x"} {
+		if _, err := Lookup(&ticket, path); err == nil {
+			t.Errorf("%s: expected an error", path)
+		}
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	columns := ParseColumns("id, fields.name,,phid")
+	if len(columns) != 3 || columns[1].Header != "fields.name" || columns[1].Path != "fields.name" {
+		t.Errorf("Unexpected columns %+v", columns)
+	}
+}
+
+func TestDefaultColumns(t *testing.T) {
+	project := &phabTypes.Project{Id: 3, Type: "PROJ"}
+	project.Fields.Name = "Backend"
+	columns := DefaultColumns(project)
+	if len(columns) != 1 || columns[0].Text(project) != "[PROJ|3]: Backend" {
+		t.Errorf("Unexpected project columns %+v", columns)
+	}
+	user := &phabTypes.User{Id: 4, Phid: "PHID-USER-4"}
+	columns = DefaultColumns(user)
+	if len(columns) != 2 || columns[0].Text(user) != "4" || columns[1].Text(user) != "PHID-USER-4" {
+		t.Errorf("Unexpected user columns %+v", columns)
+	}
+}
@@ -0,0 +1,274 @@
+// Package format renders search results as aligned tables, CSV,
+// JSON Lines or user templates
+//
+//	writer, err := format.New(os.Stdout, "csv", format.ParseColumns("id,fields.status.name"), "")
+//	if err != nil {
+//		...
+//	}
+//	ctx, cancel := context.WithCancel(ctx)
+//	defer cancel()
+//	err = format.Render(writer, phab.CallSearch(ctx, maniphest.Endpoint, args, phabTypes.Ticket{}))
+package format
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// Formats known to New
+var Formats = []string{"table", "csv", "json", "ndjson", "template"}
+
+// Writer writes objects in a single format. Flush must be called
+// after the last object, some formats only write output then.
+type Writer interface {
+	Write(object interface{}) error
+	Flush() error
+}
+
+// New creates a Writer of FORMAT, one of Formats, writing to OUT.
+// Tables and CSV use COLUMNS, or DefaultColumns of the first object
+// if there are none. The template format needs TEXT.
+func New(out io.Writer, format string, columns []Column, text string) (Writer, error) {
+	switch format {
+	case "table":
+		return NewTable(out, columns...), nil
+	case "csv":
+		return NewCSV(out, columns...), nil
+	case "json":
+		return NewJSON(out), nil
+	case "ndjson":
+		return NewJSONLines(out), nil
+	case "template":
+		return NewTemplate(out, text)
+	}
+	return nil, fmt.Errorf("Unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Render writes all RESULTS with WRITER and flushes it. It stops at
+// the first error among the results, like those sent by CallSearch.
+// The rest of RESULTS is then discarded in the background, cancel
+// the context of the search to stop it from fetching more pages.
+func Render(writer Writer, results <-chan interface{}) error {
+	for result := range results {
+		err, isErr := result.(error)
+		if !isErr {
+			err = writer.Write(result)
+		}
+		if err != nil {
+			go drain(results)
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// drain discards RESULTS, so that their sender isn't blocked forever
+func drain(results <-chan interface{}) {
+	for range results {
+	}
+}
+
+// rows writes header and rows of table-like formats
+type rows struct {
+	columns []Column
+	header  bool
+	// Formats header names, e.g. strings.ToUpper, if set
+	headerCase func(string) string
+	write      func(cells []string) error
+}
+
+// writeHeader writes the header before the first row, or in Flush
+// if there are no rows
+func (r *rows) writeHeader() error {
+	if r.header {
+		return nil
+	}
+	r.header = true
+	headers := make([]string, len(r.columns))
+	for i, col := range r.columns {
+		headers[i] = col.Header
+		if r.headerCase != nil {
+			headers[i] = r.headerCase(col.Header)
+		}
+	}
+	return r.write(headers)
+}
+
+func (r *rows) writeRow(object interface{}) error {
+	if r.columns == nil {
+		r.columns = DefaultColumns(object)
+	}
+	if err := r.writeHeader(); err != nil {
+		return err
+	}
+	cells := make([]string, len(r.columns))
+	for i, col := range r.columns {
+		cells[i] = col.Text(object)
+	}
+	return r.write(cells)
+}
+
+// Table writes objects as a table with aligned columns
+type Table struct {
+	rows
+	writer *tabwriter.Writer
+}
+
+// NewTable creates a Table writing COLUMNS to OUT
+func NewTable(out io.Writer, columns ...Column) *Table {
+	t := &Table{writer: tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)}
+	t.columns = columns
+	t.headerCase = strings.ToUpper
+	t.write = func(cells []string) error {
+		for i, cell := range cells {
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cell)
+		}
+		_, err := fmt.Fprintln(t.writer, strings.Join(cells, "\t"))
+		return err
+	}
+	return t
+}
+
+// Write adds a row of OBJECT
+func (t *Table) Write(object interface{}) error {
+	return t.writeRow(object)
+}
+
+// Flush aligns and writes all rows
+func (t *Table) Flush() error {
+	if t.columns != nil {
+		if err := t.writeHeader(); err != nil {
+			return err
+		}
+	}
+	return t.writer.Flush()
+}
+
+// CSV writes objects as comma-separated values with a header line
+type CSV struct {
+	rows
+	writer *csv.Writer
+}
+
+// NewCSV creates a CSV writing COLUMNS to OUT
+func NewCSV(out io.Writer, columns ...Column) *CSV {
+	c := &CSV{writer: csv.NewWriter(out)}
+	c.columns = columns
+	c.write = c.writer.Write
+	return c
+}
+
+// OmitHeader skips the header line, e.g. when appending to a file
+// that already has one
+func (c *CSV) OmitHeader() {
+	c.header = true
+}
+
+// Write writes a record of OBJECT
+func (c *CSV) Write(object interface{}) error {
+	return c.writeRow(object)
+}
+
+// Flush writes any buffered records
+func (c *CSV) Flush() error {
+	if c.columns != nil {
+		if err := c.writeHeader(); err != nil {
+			return err
+		}
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// JSONLines writes every object as JSON on a line of its own (NDJSON)
+type JSONLines struct {
+	encoder *json.Encoder
+}
+
+// NewJSONLines creates a JSONLines writing to OUT
+func NewJSONLines(out io.Writer) *JSONLines {
+	return &JSONLines{encoder: json.NewEncoder(out)}
+}
+
+// Write writes OBJECT right away
+func (j *JSONLines) Write(object interface{}) error {
+	return j.encoder.Encode(object)
+}
+
+// Flush does nothing, objects are never buffered
+func (j *JSONLines) Flush() error {
+	return nil
+}
+
+// JSON writes all objects as a single indented JSON list
+type JSON struct {
+	out     io.Writer
+	objects []interface{}
+}
+
+// NewJSON creates a JSON writing to OUT
+func NewJSON(out io.Writer) *JSON {
+	return &JSON{out: out, objects: []interface{}{}}
+}
+
+// Write adds OBJECT to the list
+func (j *JSON) Write(object interface{}) error {
+	j.objects = append(j.objects, object)
+	return nil
+}
+
+// Flush writes the list
+func (j *JSON) Flush() error {
+	encoder := json.NewEncoder(j.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(j.objects)
+}
+
+// Template executes a text/template for every object. A newline is
+// added after each object unless the template ends with one.
+type Template struct {
+	out      io.Writer
+	template *template.Template
+	newline  bool
+}
+
+// Functions available in templates besides the text/template builtins
+var templateFuncs = template.FuncMap{
+	"join":   strings.Join,
+	"lookup": Lookup,
+	"text":   Text,
+}
+
+// NewTemplate parses TEXT into a Template writing to OUT
+func NewTemplate(out io.Writer, text string) (*Template, error) {
+	if text == "" {
+		text = "{{text .}}"
+	}
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid template: %v", err)
+	}
+	return &Template{out: out, template: tmpl, newline: !strings.HasSuffix(text, "\n")}, nil
+}
+
+// Write executes the template for OBJECT
+func (t *Template) Write(object interface{}) error {
+	if err := t.template.Execute(t.out, object); err != nil {
+		return err
+	}
+	if t.newline {
+		_, err := io.WriteString(t.out, "\n")
+		return err
+	}
+	return nil
+}
+
+// Flush does nothing, objects are never buffered
+func (t *Template) Flush() error {
+	return nil
+}
@@ -0,0 +1,108 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func tickets() []interface{} {
+	var first, second phabTypes.Ticket
+	first.Id = 1
+	first.Fields.Name = "Crash, on start"
+	first.Fields.Status.Name = "Open"
+	second.Id = 12
+	second.Fields.Name = "Typo"
+	second.Fields.Status.Name = "Resolved"
+	return []interface{}{&first, &second}
+}
+
+func render(t *testing.T, format string, columns []Column, text string, objects []interface{}) string {
+	t.Helper()
+	var out bytes.Buffer
+	writer, err := New(&out, format, columns, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan interface{}, len(objects))
+	for _, object := range objects {
+		results <- object
+	}
+	close(results)
+	if err := Render(writer, results); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestFormats(t *testing.T) {
+	columns := ParseColumns("id,fields.status.name,fields.name")
+	tests := []struct {
+		format   string
+		columns  []Column
+		text     string
+		expected string
+	}{
+		{"table", columns, "", "" +
+			"ID  FIELDS.STATUS.NAME  FIELDS.NAME\n" +
+			"1   Open                Crash, on start\n" +
+			"12  Resolved            Typo\n"},
+		{"table", nil, "", "OBJECT\nT1: Crash, on start\nT12: Typo\n"},
+		{"csv", columns, "", "" +
+			"id,fields.status.name,fields.name\n" +
+			"1,Open,\"Crash, on start\"\n" +
+			"12,Resolved,Typo\n"},
+		{"template", nil, "{{.Id}} {{.Fields.Status.Name}}", "1 Open\n12 Resolved\n"},
+		{"template", nil, `{{lookup . "fields.name"}}|`, "Crash, on start|\nTypo|\n"},
+		{"template", nil, "", "T1: Crash, on start\nT12: Typo\n"},
+	}
+	for _, test := range tests {
+		if got := render(t, test.format, test.columns, test.text, tickets()); got != test.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.format, test.expected, got)
+		}
+	}
+
+	ndjson := render(t, "ndjson", nil, "", tickets())
+	if lines := bytes.Count([]byte(ndjson), []byte("\n")); lines != 2 || !bytes.HasPrefix([]byte(ndjson), []byte(`{"id":1,`)) {
+		t.Errorf("Unexpected JSON lines:\n%s", ndjson)
+	}
+	if got := render(t, "json", nil, "", nil); got != "[]\n" {
+		t.Errorf("Unexpected empty JSON %q", got)
+	}
+	if got := render(t, "csv", columns, "", nil); got != "id,fields.status.name,fields.name\n" {
+		t.Errorf("Unexpected empty CSV %q", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", nil, ""); err == nil {
+		t.Error("Expected an unknown format error")
+	}
+	if _, err := New(&bytes.Buffer{}, "template", nil, "{{.Id"); err == nil {
+		t.Error("Expected a template error")
+	}
+
+	failure := errors.New("Search failed")
+	results := make(chan interface{})
+	sent := make(chan struct{})
+	go func() {
+		results <- tickets()[0]
+		results <- failure
+		// The sender must not block on results after the error
+		results <- tickets()[1]
+		close(results)
+		close(sent)
+	}()
+	var out bytes.Buffer
+	if err := Render(NewTable(&out), results); err != failure {
+		t.Errorf("Expected the search error, got %v", err)
+	}
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Error("Remaining results not drained")
+	}
+}
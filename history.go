@@ -0,0 +1,59 @@
+package phabricator
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// transactionSearch is the endpoint of object histories
+const transactionSearch = "transaction.search"
+
+// Transactions returns the history of an object, oldest first,
+// following all result pages. Only transactions of ARGS.Types are
+// returned if any are given, see the transaction package for a builder.
+func (p *Phabricator) Transactions(ctx context.Context, args phabTypes.TransactionSearchArgs) ([]*phabTypes.Transaction, error) {
+	if args.ObjectIdentifier == "" && len(args.Constraints.Phids) == 0 {
+		msg := "Transaction search needs an object identifier or PHIDs"
+		logger.Error(msg)
+		return nil, errors.New(msg)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := p.CallSearch(ctx, transactionSearch, args, phabTypes.Transaction{})
+	if results == nil {
+		msg := "Endpoint transaction.search is not available"
+		logger.Error(msg)
+		return nil, errors.New(msg)
+	}
+	types := make(map[phabTypes.TransactionType]bool, len(args.Types))
+	for _, typ := range args.Types {
+		types[typ] = true
+	}
+	var transactions []*phabTypes.Transaction
+	for result := range results {
+		if err, isErr := result.(error); isErr {
+			logger.WithFields(log.Fields{
+				"error":  err,
+				"object": args.ObjectIdentifier,
+			}).Error("Failed to search transactions")
+			cancel()
+			drain(results)
+			return nil, err
+		}
+		transaction := result.(*phabTypes.Transaction)
+		if len(types) == 0 || types[transaction.Type] {
+			transactions = append(transactions, transaction)
+		}
+	}
+	// Pages are decoded concurrently, so results arrive in any order
+	sort.Slice(transactions, func(i, j int) bool {
+		return transactions[i].Id < transactions[j].Id
+	})
+	return transactions, nil
+}
@@ -0,0 +1,100 @@
+package phabricator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Names resolved by a single phid.lookup call
+	lookupBatchSize = 100
+)
+
+// ObjectHandle describes an object as returned by phid.lookup and phid.query
+type ObjectHandle struct {
+	PHID     string `json:"phid"`
+	URI      string `json:"uri"`
+	TypeName string `json:"typeName"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	FullName string `json:"fullName"`
+	Status   string `json:"status"`
+}
+
+// MonogramResolver translates monograms and object URLs to objects
+// using phid.lookup. Lookups are batched and their results cached.
+type MonogramResolver struct {
+	phab  *Phabricator
+	cache *ttlCache
+}
+
+// NewMonogramResolver creates a resolver using PHAB. Results are
+// cached for TTL, a zero TTL caches them for the resolver's lifetime.
+func NewMonogramResolver(phab *Phabricator, ttl time.Duration) *MonogramResolver {
+	return &MonogramResolver{phab: phab, cache: newTTLCache(ttl)}
+}
+
+// Resolve looks up monograms or object URLs in INPUTS. The result is keyed
+// by the original input. Inputs that can't be parsed make the whole call
+// fail, while objects that don't exist or aren't visible are just missing
+// from the result.
+func (r *MonogramResolver) Resolve(ctx context.Context, inputs ...string) (map[string]*ObjectHandle, error) {
+	result := make(map[string]*ObjectHandle, len(inputs))
+	canonical := make(map[string][]string) // canonical name -> inputs
+	var missing []string
+	for _, input := range inputs {
+		monogram, err := ParseMonogram(input)
+		if err != nil {
+			return nil, err
+		}
+		name := monogram.String()
+		if cached, hit := r.cache.get(name); hit {
+			result[input] = cached.(*ObjectHandle)
+			continue
+		}
+		if _, pending := canonical[name]; !pending {
+			missing = append(missing, name)
+		}
+		canonical[name] = append(canonical[name], input)
+	}
+
+	for start := 0; start < len(missing); start += lookupBatchSize {
+		end := start + lookupBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		handles, err := r.phab.lookup(ctx, missing[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for name, handle := range handles {
+			r.cache.set(name, handle)
+			for _, input := range canonical[name] {
+				result[input] = handle
+			}
+		}
+	}
+	return result, nil
+}
+
+// lookup calls phid.lookup with NAMES
+func (p *Phabricator) lookup(ctx context.Context, names []string) (map[string]*ObjectHandle, error) {
+	params := url.Values{}
+	for i, name := range names {
+		params.Set(fmt.Sprintf("names[%d]", i), name)
+	}
+	handles := make(map[string]*ObjectHandle)
+	if err := p.call(ctx, "phid.lookup", params.Encode(), &handles); err != nil {
+		return nil, err
+	}
+	logger.WithFields(log.Fields{
+		"requested": len(names),
+		"found":     len(handles),
+	}).Debug("Looked up object names")
+	return handles, nil
+}
@@ -0,0 +1,168 @@
+// Package maniphest builds arguments for maniphest.search
+//
+//	args := maniphest.Query().
+//		Status(phabTypes.TicketStatusOpen).
+//		AssignedTo(me).
+//		InProjects(backend).
+//		Order("priority").
+//		WithSubscribers().
+//		Args()
+//	results := phab.CallSearch(ctx, maniphest.Endpoint, args, phabTypes.Ticket{})
+package maniphest
+
+import (
+	"time"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// Endpoint the built arguments are meant for
+const Endpoint = "maniphest.search"
+
+// Builder builds TicketSearchArgs. Every method modifies the builder
+// and returns it, so calls can be chained.
+type Builder struct {
+	args phabTypes.TicketSearchArgs
+}
+
+// Query starts a new, unconstrained task query
+func Query() *Builder {
+	return &Builder{}
+}
+
+// Args returns the built arguments
+func (b *Builder) Args() phabTypes.TicketSearchArgs {
+	return b.args
+}
+
+// QueryKey starts from a saved or builtin query, e.g. open or authored
+func (b *Builder) QueryKey(key string) *Builder {
+	b.args.QueryKey = key
+	return b
+}
+
+// IDs limits the results to tasks with IDS
+func (b *Builder) IDs(ids ...int) *Builder {
+	b.args.Constraints.Ids = append(b.args.Constraints.Ids, ids...)
+	return b
+}
+
+// PHIDs limits the results to tasks with PHIDS
+func (b *Builder) PHIDs(phids ...string) *Builder {
+	b.args.Constraints.Phids = append(b.args.Constraints.Phids, phids...)
+	return b
+}
+
+// Status limits the results to tasks in any of STATUSES
+func (b *Builder) Status(statuses ...phabTypes.TicketStatus) *Builder {
+	b.args.Constraints.Statuses = append(b.args.Constraints.Statuses, statuses...)
+	return b
+}
+
+// Priority limits the results to tasks with any of PRIORITIES
+func (b *Builder) Priority(priorities ...phabTypes.TicketPriority) *Builder {
+	b.args.Constraints.Priorities = append(b.args.Constraints.Priorities, priorities...)
+	return b
+}
+
+// AssignedTo limits the results to tasks owned by any of the users with PHIDS
+func (b *Builder) AssignedTo(phids ...string) *Builder {
+	b.args.Constraints.Assigned = append(b.args.Constraints.Assigned, phids...)
+	return b
+}
+
+// AuthoredBy limits the results to tasks created by any of the users with PHIDS
+func (b *Builder) AuthoredBy(phids ...string) *Builder {
+	b.args.Constraints.AuthorPHIDs = append(b.args.Constraints.AuthorPHIDs, phids...)
+	return b
+}
+
+// SubscribedBy limits the results to tasks any of PHIDS is subscribed to
+func (b *Builder) SubscribedBy(phids ...string) *Builder {
+	b.args.Constraints.Subscribers = append(b.args.Constraints.Subscribers, phids...)
+	return b
+}
+
+// InProjects limits the results to tasks tagged with all of the projects with PHIDS
+func (b *Builder) InProjects(phids ...string) *Builder {
+	b.args.Constraints.Projects = append(b.args.Constraints.Projects, phids...)
+	return b
+}
+
+// InColumns limits the results to tasks in any of the workboard columns with PHIDS
+func (b *Builder) InColumns(phids ...string) *Builder {
+	b.args.Constraints.ColumnPHIDs = append(b.args.Constraints.ColumnPHIDs, phids...)
+	return b
+}
+
+// InSpaces limits the results to tasks in any of the spaces with PHIDS
+func (b *Builder) InSpaces(phids ...string) *Builder {
+	b.args.Constraints.Spaces = append(b.args.Constraints.Spaces, phids...)
+	return b
+}
+
+// Matching limits the results to tasks matching the full-text query TEXT
+func (b *Builder) Matching(text string) *Builder {
+	b.args.Constraints.Query = text
+	return b
+}
+
+// CreatedAfter limits the results to tasks created at or after T
+func (b *Builder) CreatedAfter(t time.Time) *Builder {
+	b.args.Constraints.CreatedStart = t.Unix()
+	return b
+}
+
+// CreatedBefore limits the results to tasks created at or before T
+func (b *Builder) CreatedBefore(t time.Time) *Builder {
+	b.args.Constraints.CreatedEnd = t.Unix()
+	return b
+}
+
+// ModifiedAfter limits the results to tasks modified at or after T
+func (b *Builder) ModifiedAfter(t time.Time) *Builder {
+	b.args.Constraints.ModifiedStart = t.Unix()
+	return b
+}
+
+// ModifiedBefore limits the results to tasks modified at or before T
+func (b *Builder) ModifiedBefore(t time.Time) *Builder {
+	b.args.Constraints.ModifiedEnd = t.Unix()
+	return b
+}
+
+// ClosedAfter limits the results to tasks closed at or after T
+func (b *Builder) ClosedAfter(t time.Time) *Builder {
+	b.args.Constraints.ClosedStart = t.Unix()
+	return b
+}
+
+// ClosedBefore limits the results to tasks closed at or before T
+func (b *Builder) ClosedBefore(t time.Time) *Builder {
+	b.args.Constraints.ClosedEnd = t.Unix()
+	return b
+}
+
+// Order sets the result order, e.g. priority, updated or title
+func (b *Builder) Order(order string) *Builder {
+	b.args.Order = order
+	return b
+}
+
+// WithColumns attaches the workboard columns of the tasks
+func (b *Builder) WithColumns() *Builder {
+	b.args.Attachments.Columns = true
+	return b
+}
+
+// WithSubscribers attaches the subscribers of the tasks
+func (b *Builder) WithSubscribers() *Builder {
+	b.args.Attachments.Subscribers = true
+	return b
+}
+
+// WithProjects attaches the projects the tasks are tagged with
+func (b *Builder) WithProjects() *Builder {
+	b.args.Attachments.Projects = true
+	return b
+}
@@ -0,0 +1,44 @@
+package maniphest
+
+import (
+	"testing"
+	"time"
+
+	query "github.com/google/go-querystring/query"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func TestQuery(t *testing.T) {
+	week := time.Unix(1600000000, 0)
+	args := Query().
+		Status(phabTypes.TicketStatusOpen).
+		AssignedTo("PHID-USER-me").
+		InProjects("PHID-PROJ-backend", "PHID-PROJ-api").
+		CreatedAfter(week).
+		Order("priority").
+		WithSubscribers().
+		Args()
+
+	values, err := query.Values(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"constraints[statuses][]":   "open",
+		"constraints[assigned][]":   "PHID-USER-me",
+		"constraints[createdStart]": "1600000000",
+		"order":                     "priority",
+		"attachments[subscribers]":  "true",
+	} {
+		if value := values.Get(key); value != expected {
+			t.Errorf("%s: expected %q, got %q", key, expected, value)
+		}
+	}
+	if projects := values["constraints[projects][]"]; len(projects) != 2 {
+		t.Errorf("Expected two projects, got %v", projects)
+	}
+	if _, set := values["attachments[columns]"]; set {
+		t.Error("Columns attached without being asked for")
+	}
+}
@@ -0,0 +1,144 @@
+package phabricator
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Applications of the numeric monograms, e.g. T123
+var monogramApplications = map[string]string{
+	"B": "Harbormaster Buildable",
+	"C": "Countdown",
+	"D": "Differential Revision",
+	"E": "Calendar Event",
+	"F": "File",
+	"H": "Herald Rule",
+	"I": "Fund Initiative",
+	"K": "Passphrase Credential",
+	"L": "Legalpad Document",
+	"M": "Pholio Mock",
+	"P": "Paste",
+	"Q": "Ponder Question",
+	"R": "Diffusion Repository",
+	"S": "Space",
+	"T": "Maniphest Task",
+	"U": "Phurl URL",
+	"V": "Slowvote Poll",
+	"W": "Dashboard Panel",
+	"Z": "Conpherence Room",
+}
+
+var (
+	numericMonogram = regexp.MustCompile(`^([A-Z])([1-9][0-9]*)$`)
+	// R12:abcdef - a commit in a repository identified by its ID
+	repoIDCommit = regexp.MustCompile(`^R([1-9][0-9]*):([0-9a-f]{5,40})$`)
+	// rXYZ or rXYZabcdef - a repository or a commit by callsign
+	callsignMonogram = regexp.MustCompile(`^r([A-Z]+)([0-9a-f]{5,40})?$`)
+	userMonogram     = regexp.MustCompile(`^@([^\s@#]+)$`)
+	projectMonogram  = regexp.MustCompile(`^#([^\s@#]+)$`)
+)
+
+// Monogram is a parsed object name as Phabricator users write it,
+// e.g. T123, D45, rXYZ, rXYZabcdef, P9, @user or #project
+type Monogram struct {
+	// Prefix of the monogram: a capital letter, "r", "@" or "#"
+	Prefix string
+	// Object ID of numeric monograms
+	ID int
+	// Callsign, username or project slug
+	Name string
+	// Commit hash of commit monograms
+	Commit string
+}
+
+// String returns the canonical form understood by phid.lookup
+func (m Monogram) String() string {
+	switch {
+	case m.Prefix == "R" && m.Commit != "":
+		return fmt.Sprintf("R%d:%s", m.ID, m.Commit)
+	case m.Prefix == "r":
+		return "r" + m.Name + m.Commit
+	case m.Prefix == "@" || m.Prefix == "#":
+		return m.Prefix + m.Name
+	default:
+		return fmt.Sprintf("%s%d", m.Prefix, m.ID)
+	}
+}
+
+// Application describes what kind of object the monogram names
+func (m Monogram) Application() string {
+	switch m.Prefix {
+	case "@":
+		return "User"
+	case "#":
+		return "Project"
+	case "r":
+		if m.Commit != "" {
+			return "Diffusion Commit"
+		}
+		return "Diffusion Repository"
+	case "R":
+		if m.Commit != "" {
+			return "Diffusion Commit"
+		}
+	}
+	return monogramApplications[m.Prefix]
+}
+
+// ParseMonogram parses S, which is either a monogram or an object URL
+func ParseMonogram(s string) (Monogram, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "://") {
+		return ParseObjectURL(s)
+	}
+	if match := numericMonogram.FindStringSubmatch(s); match != nil {
+		if _, known := monogramApplications[match[1]]; known {
+			id, _ := strconv.Atoi(match[2])
+			return Monogram{Prefix: match[1], ID: id}, nil
+		}
+	}
+	if match := repoIDCommit.FindStringSubmatch(s); match != nil {
+		id, _ := strconv.Atoi(match[1])
+		return Monogram{Prefix: "R", ID: id, Commit: match[2]}, nil
+	}
+	if match := callsignMonogram.FindStringSubmatch(s); match != nil {
+		return Monogram{Prefix: "r", Name: match[1], Commit: match[2]}, nil
+	}
+	if match := userMonogram.FindStringSubmatch(s); match != nil {
+		return Monogram{Prefix: "@", Name: match[1]}, nil
+	}
+	if match := projectMonogram.FindStringSubmatch(s); match != nil {
+		return Monogram{Prefix: "#", Name: strings.ToLower(match[1])}, nil
+	}
+	return Monogram{}, fmt.Errorf("Not a Phabricator monogram: %q", s)
+}
+
+// ParseObjectURL extracts the monogram from an object URL, e.g.
+// https://phab.example.com/T123, .../D45?id=2, .../p/alice/,
+// .../tag/backend/ or .../diffusion/XYZ/history/
+func ParseObjectURL(objectURL string) (Monogram, error) {
+	u, err := url.Parse(objectURL)
+	if err != nil {
+		return Monogram{}, err
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return ParseMonogram(parts[0])
+	case len(parts) >= 2 && parts[0] == "p":
+		return Monogram{Prefix: "@", Name: parts[1]}, nil
+	case len(parts) >= 2 && parts[0] == "tag":
+		return Monogram{Prefix: "#", Name: parts[1]}, nil
+	case len(parts) >= 2 && parts[0] == "diffusion":
+		if id, err := strconv.Atoi(parts[1]); err == nil {
+			return Monogram{Prefix: "R", ID: id}, nil
+		}
+		if callsignMonogram.MatchString("r" + parts[1]) {
+			return Monogram{Prefix: "r", Name: parts[1]}, nil
+		}
+	}
+	return Monogram{}, fmt.Errorf("No Phabricator object in URL %q", objectURL)
+}
@@ -0,0 +1,112 @@
+package phabricator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseMonogram(t *testing.T) {
+	for input, expected := range map[string]string{
+		"T123":                                 "T123",
+		" D45 ":                                "D45",
+		"P9":                                   "P9",
+		"rXYZ":                                 "rXYZ",
+		"rXYZ1a2b3c4d":                         "rXYZ1a2b3c4d",
+		"R12:1a2b3c4d":                         "R12:1a2b3c4d",
+		"@alice":                               "@alice",
+		"#Backend":                             "#backend",
+		"https://phab.example.com/T123":        "T123",
+		"https://phab.example.com/D45?id=7":    "D45",
+		"https://phab.example.com/p/alice/":    "@alice",
+		"https://phab.example.com/tag/backend": "#backend",
+		"https://phab.example.com/diffusion/XYZ/history/master/": "rXYZ",
+		"https://phab.example.com/diffusion/12/":                 "R12",
+	} {
+		monogram, err := ParseMonogram(input)
+		if err != nil {
+			t.Errorf("%q: %s", input, err)
+			continue
+		}
+		if monogram.String() != expected {
+			t.Errorf("%q parsed as %q, expected %q", input, monogram, expected)
+		}
+	}
+
+	for _, input := range []string{"", "T", "T0", "A12", "x12", "@", "hello world", "https://phab.example.com/project/view/1/"} {
+		if _, err := ParseMonogram(input); err == nil {
+			t.Errorf("%q accepted as a monogram", input)
+		}
+	}
+}
+
+func TestMonogramApplication(t *testing.T) {
+	for input, expected := range map[string]string{
+		"T1":        "Maniphest Task",
+		"D1":        "Differential Revision",
+		"rXYZ":      "Diffusion Repository",
+		"rXYZabcde": "Diffusion Commit",
+		"@bob":      "User",
+	} {
+		monogram, _ := ParseMonogram(input)
+		if app := monogram.Application(); app != expected {
+			t.Errorf("%s: expected %s, got %s", input, expected, app)
+		}
+	}
+}
+
+func TestMonogramResolver(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		r.ParseForm()
+		if r.Form.Get("names[0]") == "T404" {
+			fmt.Fprint(w, `{"result": []}`)
+			return
+		}
+		fmt.Fprint(w, `{"result": {
+			"T1": {"phid": "PHID-TASK-1", "type": "TASK", "name": "T1", "fullName": "T1: Fix it"},
+			"@alice": {"phid": "PHID-USER-alice", "type": "USER", "name": "alice"}
+		}}`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	phab, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-token"), WithLogLevel("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewMonogramResolver(phab, time.Minute)
+	handles, err := resolver.Resolve(ctx, "T1", server.URL+"/T1", "@alice", "T2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handles["T1"].PHID != "PHID-TASK-1" || handles[server.URL+"/T1"] != handles["T1"] {
+		t.Errorf("Task not resolved: %v", handles)
+	}
+	if handles["@alice"].PHID != "PHID-USER-alice" {
+		t.Errorf("User not resolved: %v", handles)
+	}
+	if _, found := handles["T2"]; found {
+		t.Error("Unknown object resolved")
+	}
+
+	if _, err := resolver.Resolve(ctx, "T1", "@alice"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected a single batched request, got %d", n)
+	}
+
+	handles, err = resolver.Resolve(ctx, "T404")
+	if err != nil || len(handles) != 0 {
+		t.Errorf("Empty lookup result mishandled: %v (%v)", handles, err)
+	}
+	if _, err := resolver.Resolve(ctx, "not a monogram"); err == nil {
+		t.Error("Invalid monogram accepted")
+	}
+}
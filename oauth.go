@@ -0,0 +1,166 @@
+package phabricator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+)
+
+// OAuthConfig describes an OAuth server application registered
+// in Phabricator under /oauthserver/. It implements the authorization code
+// flow, so that tools can call Conduit on behalf of the logged-in user.
+type OAuthConfig struct {
+	// Root of your Phabricator instance, e.g. https://phab.example.com/
+	BaseURL string
+	// PHID of the OAuth application
+	ClientID     string
+	ClientSecret string
+	// Must match the redirect URI configured in Phabricator
+	RedirectURL string
+	// Optional, Phabricator doesn't require any scope
+	Scope string
+	// HTTP client used for the token requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// OAuthToken is the response of the token endpoint. Use AccessToken
+// with WithAccessToken to authenticate Conduit calls. Phabricator
+// doesn't issue refresh tokens, once the token expires the user
+// has to go through the authorization again.
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	// Computed from ExpiresIn when the token is received
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// Expired reports whether the access token is past its expiry.
+// Tokens without an expiry never expire.
+func (t *OAuthToken) Expired() bool {
+	return !t.Expiry.IsZero() && time.Now().After(t.Expiry)
+}
+
+type oauthErrorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// OAuthError is returned when the token endpoint rejects a request
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return fmt.Sprintf("OAuth error [%s] %s", e.Code, e.Description)
+}
+
+func (c *OAuthConfig) endpoint(path string) (string, error) {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	ref, _ := url.Parse(path)
+	return base.ResolveReference(ref).String(), nil
+}
+
+// AuthCodeURL returns the URL to redirect the user to. Phabricator
+// redirects back to RedirectURL with a code to pass to Exchange.
+// STATE should be an unguessable value verified on the way back.
+func (c *OAuthConfig) AuthCodeURL(state string) (string, error) {
+	authURL, err := c.endpoint("/oauthserver/auth/")
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{
+		"response_type": {"code"},
+		"client_id":     {c.ClientID},
+		"redirect_uri":  {c.RedirectURL},
+		"state":         {state},
+	}
+	if c.Scope != "" {
+		params.Set("scope", c.Scope)
+	}
+	return authURL + "?" + params.Encode(), nil
+}
+
+// Exchange trades the authorization CODE for an access token
+func (c *OAuthConfig) Exchange(ctx context.Context, code string) (*OAuthToken, error) {
+	return c.requestToken(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {c.RedirectURL},
+	})
+}
+
+func (c *OAuthConfig) requestToken(ctx context.Context, params url.Values) (*OAuthToken, error) {
+	tokenURL, err := c.endpoint("/oauthserver/token/")
+	if err != nil {
+		return nil, err
+	}
+	params.Set("client_id", c.ClientID)
+	params.Set("client_secret", c.ClientSecret)
+
+	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"error":    err,
+			"endpoint": tokenURL,
+		}).Error("OAuth token request failed")
+		return nil, err
+	}
+	defer resp.Body.Close()
+	logger.WithFields(log.Fields{
+		"status":     resp.Status,
+		"endpoint":   tokenURL,
+		"grant_type": params.Get("grant_type"),
+	}).Info("OAuth token request")
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var oauthErr oauthErrorResponse
+	if err := json.Unmarshal(body, &oauthErr); err == nil && oauthErr.Error != "" {
+		logger.WithFields(log.Fields{
+			"OAuthError":            oauthErr.Error,
+			"OAuthErrorDescription": oauthErr.Description,
+		}).Error("OAuth token request rejected")
+		return nil, &OAuthError{Code: oauthErr.Error, Description: oauthErr.Description}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OAuth token request failed: %s", resp.Status)
+	}
+	var token OAuthToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		logger.WithError(err).Error("Failed to decode JSON")
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("OAuth token response has no access token")
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &token, nil
+}
@@ -0,0 +1,98 @@
+package phabricator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestOAuthAuthCodeURL(t *testing.T) {
+	cfg := OAuthConfig{
+		BaseURL:     "https://phab.example.com/",
+		ClientID:    "PHID-OASC-client",
+		RedirectURL: "https://tool.example.com/callback",
+	}
+	authURL, err := cfg.AuthCodeURL("xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Path != "/oauthserver/auth/" {
+		t.Errorf("Unexpected authorization path %s", parsed.Path)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != cfg.ClientID || query.Get("state") != "xyz" || query.Get("response_type") != "code" {
+		t.Errorf("Unexpected authorization parameters %v", query)
+	}
+}
+
+func TestOAuthExchange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauthserver/token/" {
+			http.NotFound(w, r)
+			return
+		}
+		r.ParseForm()
+		if r.Form.Get("client_secret") != "secret" {
+			fmt.Fprint(w, `{"error": "invalid_client", "error_description": "Bad secret"}`)
+			return
+		}
+		if r.Form.Get("grant_type") == "authorization_code" {
+			fmt.Fprintf(w, `{"access_token": "token-for-%s", "token_type": "Bearer", "expires_in": 3600}`, r.Form.Get("code"))
+		}
+	}))
+	defer server.Close()
+
+	cfg := OAuthConfig{BaseURL: server.URL, ClientID: "PHID-OASC-client", ClientSecret: "secret"}
+	ctx := context.Background()
+	token, err := cfg.Exchange(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "token-for-abc" || token.Expired() {
+		t.Errorf("Unexpected token %+v", token)
+	}
+	cfg.ClientSecret = "wrong"
+	_, err = cfg.Exchange(ctx, "abc")
+	if oauthErr, ok := err.(*OAuthError); !ok || oauthErr.Code != "invalid_client" {
+		t.Errorf("Expected an invalid_client OAuthError, got %v", err)
+	}
+}
+
+func TestAccessTokenAuth(t *testing.T) {
+	phab, err := New(context.Background(),
+		WithAPI("https://phab.example.com/api/"),
+		WithAccessToken("oauth-token"),
+		WithLogLevel("error"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := phab.authValues()
+	if auth.Get("access_token") != "oauth-token" || auth.Get("api.token") != "" {
+		t.Errorf("Unexpected auth parameters %v", auth)
+	}
+}
+
+func TestAccessTokenOverridesTokenSource(t *testing.T) {
+	source := StaticTokenSource{Token: "api-from-source"}
+	phab, err := New(context.Background(),
+		WithAPI("https://phab.example.com/api/"),
+		WithAccessToken("oauth-token"),
+		WithTokenSource(source),
+		WithLogLevel("error"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := phab.authValues()
+	if auth.Get("access_token") != "oauth-token" || auth.Get("api.token") != "" {
+		t.Errorf("TokenSource used despite an access token: %v", auth)
+	}
+}
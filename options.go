@@ -0,0 +1,158 @@
+package phabricator
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Option configures a Phabricator instance created by New
+type Option func(*PhabOptions)
+
+// WithOptions copies all settings from OPTS. Options given after it
+// override the copied values.
+func WithOptions(opts PhabOptions) Option {
+	return func(o *PhabOptions) {
+		*o = opts
+	}
+}
+
+// WithAPI sets the root of the Conduit API, e.g. https://phab.example.com/api/
+func WithAPI(api string) Option {
+	return func(o *PhabOptions) {
+		o.API = api
+	}
+}
+
+// WithToken sets the Conduit API token. Requires WithAPI.
+func WithToken(token string) Option {
+	return func(o *PhabOptions) {
+		o.Token = token
+	}
+}
+
+// WithAccessToken authenticates with an OAuth access token
+// instead of an API token. Requires WithAPI.
+func WithAccessToken(accessToken string) Option {
+	return func(o *PhabOptions) {
+		o.AccessToken = accessToken
+	}
+}
+
+// WithTokenSource looks up credentials with SOURCE, e.g. a ChainTokenSource
+// of environment, files and .arcrc. WithToken and WithAccessToken win over it.
+func WithTokenSource(source TokenSource) Option {
+	return func(o *PhabOptions) {
+		o.TokenSource = source
+	}
+}
+
+// WithLogLevel sets a LogRus compatible log level
+func WithLogLevel(level string) Option {
+	return func(o *PhabOptions) {
+		o.LogLevel = level
+	}
+}
+
+// WithTimeout sets the timeout of each HTTP request
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *PhabOptions) {
+		o.Timeout = timeout
+	}
+}
+
+// WithLogOutput redirects the logger output to OUT
+func WithLogOutput(out io.Writer) Option {
+	return func(o *PhabOptions) {
+		o.Out = out
+	}
+}
+
+// WithArcrc reads the arc configuration from ARCRC instead of ~/.arcrc
+func WithArcrc(arcrc io.Reader) Option {
+	return func(o *PhabOptions) {
+		o.Arcrc = arcrc
+	}
+}
+
+// WithHTTPClient sends all requests through CLIENT
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *PhabOptions) {
+		o.HTTPClient = client
+	}
+}
+
+// WithTransport sends all requests through TRANSPORT
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *PhabOptions) {
+		o.Transport = transport
+	}
+}
+
+// WithRetries retries requests failing with transport errors up to
+// RETRIES times, waiting WAIT before the first retry and doubling it
+// with every further one
+func WithRetries(retries int, wait time.Duration) Option {
+	return func(o *PhabOptions) {
+		o.Retries = retries
+		o.RetryWait = wait
+	}
+}
+
+// WithArgumentValidation checks call arguments against the
+// discovered endpoint parameters before sending them
+func WithArgumentValidation() Option {
+	return func(o *PhabOptions) {
+		o.ValidateArguments = true
+	}
+}
+
+// WithEndpointCache caches conduit.query responses in DIR for TTL
+func WithEndpointCache(dir string, ttl time.Duration) Option {
+	return func(o *PhabOptions) {
+		o.CacheDir = dir
+		o.CacheTTL = ttl
+	}
+}
+
+// WithEndpoints registers ENDPOINTS without any endpoint discovery
+func WithEndpoints(endpoints ...string) Option {
+	return func(o *PhabOptions) {
+		o.Endpoints = append(o.Endpoints, endpoints...)
+	}
+}
+
+// WithPreflight checks the credentials with user.whoami right away,
+// see PhabOptions.Preflight
+func WithPreflight() Option {
+	return func(o *PhabOptions) {
+		o.Preflight = true
+	}
+}
+
+// New creates a Phabricator instance configured by OPTS. Without any
+// options, the default host and its token are read from ~/.arcrc.
+//
+// Unlike Init, New doesn't contact Phabricator unless WithPreflight is used.
+// Endpoints are discovered on the first call that needs them, bound to
+// that call's context.
+func New(ctx context.Context, opts ...Option) (*Phabricator, error) {
+	var options PhabOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	p := &Phabricator{}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.configure(ctx, &options); err != nil {
+		return nil, err
+	}
+	if options.Preflight {
+		if err := p.preflight(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
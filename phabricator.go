@@ -16,11 +16,12 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httputil"
 	"net/url"
-	"os"
-	"os/user"
-	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	// https://github.com/Sirupsen/logrus
@@ -61,6 +62,8 @@ var logger = log.New()
 const (
 	// Phabricator paginates responses in pages of 100 results.
 	maxBufferedResponses = 100
+	// First wait before retrying a failed request
+	defaultRetryWait = time.Second
 )
 
 type endpointInfo struct {
@@ -85,8 +88,13 @@ func (ei endpointInfo) String() string {
 	// but if you look at the code, WriteString always returns nil... (Golang 1.11)
 	builder.WriteString(fmt.Sprintf("\tDescription: %s\n", ei.Description))
 	builder.WriteString(fmt.Sprintf("\tParams:\n"))
-	for param, desc := range ei.Params {
-		builder.WriteString(fmt.Sprintf("\t\t%s: %s\n", param, desc))
+	params := make([]string, 0, len(ei.Params))
+	for param := range ei.Params {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		builder.WriteString(fmt.Sprintf("\t\t%s: %s\n", param, ei.Params[param]))
 	}
 	builder.WriteString(fmt.Sprintf("\tReturn:\n\t\t%s\n", ei.Return))
 	return builder.String()
@@ -115,20 +123,118 @@ type Phabricator struct {
 	searchEndpoints map[string]searchEndpointCallback
 	editEndpoints   map[string]editEndpointCallback
 	client          *http.Client
+	retries         int
+	retryWait       time.Duration
+	validate        bool
+	cacheDir        string
+	cacheTTL        time.Duration
+	staticEndpoints []string
+	discoveryLock   sync.Mutex
+	sessionLock     sync.Mutex
+	viewer          *WhoAmI
+	viewerLock      sync.Mutex
+	ticketEnums     *TicketEnums
+	ticketEnumsLock sync.Mutex
+	// OAuth authentication on behalf of a user
+	accessToken string
+	// Legacy certificate authentication
+	certUser    string
+	certificate string
+	session     *conduitSession
 }
 
+// postRequest sends a Conduit call. Authentication is added here and
+// nowhere else, so postData never holds credentials and is safe to log.
 func (p *Phabricator) postRequest(ctx context.Context, endpoint, postData string) ([]byte, error) {
+	data := p.authValues().Encode()
+	if postData != "" {
+		data = data + "&" + postData
+	}
+	return p.post(ctx, endpoint, data)
+}
+
+// HTTPError is returned when Phabricator, or a proxy in front of it,
+// answers with an HTTP error status instead of a Conduit response
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	// Delay the server asked for in a Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP request failed: %s", e.Status)
+}
+
+// Temporary tells whether retrying the request may help
+func (e *HTTPError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryable tells whether ERR is a transport failure worth retrying.
+// Conduit errors never are, neither are cancelled contexts.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Temporary()
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// post sends POSTDATA to ENDPOINT as is, retrying transport failures as
+// configured with PhabOptions.Retries. Errors are scrubbed of credentials.
+func (p *Phabricator) post(ctx context.Context, endpoint, postData string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		body, err := p.postOnce(ctx, endpoint, postData)
+		if err == nil || attempt >= p.retries || !retryable(ctx, err) {
+			return body, err
+		}
+		wait := p.retryWait << uint(attempt)
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && httpErr.RetryAfter > wait {
+			wait = httpErr.RetryAfter
+		}
+		logger.WithFields(log.Fields{
+			"error":    err,
+			"endpoint": endpoint,
+			"attempt":  attempt + 1,
+			"wait":     wait,
+		}).Warn("Request failed, retrying")
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *Phabricator) postOnce(ctx context.Context, endpoint, postData string) ([]byte, error) {
 	req, err := http.NewRequest("POST", endpoint, strings.NewReader(postData))
-	req = req.WithContext(ctx)
 	// We delay error reporting to the caller, which has
 	// more human-readable data to report
 	if err != nil {
-		return nil, err
+		return nil, redactError(err)
 	}
+	req = req.WithContext(ctx)
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	if logger.IsLevelEnabled(log.TraceLevel) {
+		if dump, err := httputil.DumpRequestOut(req, true); err == nil {
+			logger.WithField("request", Redact(string(dump))).Trace("HTTP Request dump")
+		}
+	}
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, redactError(err)
 	}
 	logger.WithFields(log.Fields{
 		"status":   resp.Status,
@@ -137,12 +243,20 @@ func (p *Phabricator) postRequest(ctx context.Context, endpoint, postData string
 	}).Info("HTTP Request")
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 400 {
+		httpErr := &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			httpErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, httpErr
+	}
+
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		logger.WithFields(log.Fields{
 			"error": err,
 		}).Error("Failed to read HTTP response")
-		return nil, err
+		return nil, redactError(err)
 	}
 	return body, nil
 }
@@ -169,12 +283,11 @@ func (p *Phabricator) loadEndpoints(einfo map[string]endpointInfo) {
 	}
 }
 
-func (p *Phabricator) queryEndpoints() (map[string]endpointInfo, error) {
+func (p *Phabricator) queryEndpoints(ctx context.Context) (map[string]endpointInfo, error) {
 	endpoint := "conduit.query"
 	path, _ := url.Parse(endpoint)
 	phabConduitQuery := p.apiEndpoint.ResolveReference(path)
-	data := url.Values{"api.token": {p.apiToken}}
-	resp, err := p.client.PostForm(phabConduitQuery.String(), data)
+	body, err := p.postRequest(ctx, phabConduitQuery.String(), "")
 	if err != nil {
 		logger.WithFields(log.Fields{
 			"error":    err,
@@ -182,20 +295,7 @@ func (p *Phabricator) queryEndpoints() (map[string]endpointInfo, error) {
 		}).Error("HTTP Request failed")
 		return nil, err
 	}
-	logger.WithFields(log.Fields{
-		"status":   resp.Status,
-		"method":   resp.Request.Method,
-		"endpoint": phabConduitQuery.String(),
-	}).Info("HTTP Request")
-	defer resp.Body.Close()
 	var conduitAPI conduitQueryResponse
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		logger.WithFields(log.Fields{
-			"error": err,
-		}).Error("Failed to read HTTP response")
-		return nil, err
-	}
 	norm, exists := normalization[endpoint]
 	if exists {
 		body = bytes.Replace(body, norm.from, norm.to, -1)
@@ -214,8 +314,7 @@ func (p *Phabricator) queryEndpoints() (map[string]endpointInfo, error) {
 			"PhabricatorErrorCode": conduitAPI.ErrorCode,
 			"PhabricatorErrorInfo": conduitAPI.ErrorInfo,
 		}).Error("Invalid Phabricator Request")
-		err := fmt.Errorf("[%s] %s", conduitAPI.ErrorCode, conduitAPI.ErrorInfo)
-		return nil, err
+		return nil, conduitError(conduitAPI.ErrorCode, conduitAPI.ErrorInfo)
 	}
 	return conduitAPI.Result, nil
 }
@@ -226,11 +325,19 @@ type PhabOptions struct {
 	// Root of your Phabricator instance's API. If not specified, will be
 	// read from ~/.arcrc. If API is left empty, the library will attempt
 	// to find the API root and token based on your default Phabricator host.
+	// Like arc, it looks at phabricator.uri in .arcconfig of the working copy,
+	// then at the default in ~/.arcrc and finally in /etc/arcconfig.
 	// Run `arc get-config default` if you're unsure what your default is.
 	API string
 	// Authentication token. If empty, phabricator will try to look it up
-	// at ~/.arcrc based on API. Must be omitted if API is omitted.
+	// with TokenSource based on API. Must be omitted if API is omitted.
 	Token string
+	// OAuth access token, used instead of Token to act on behalf of
+	// a user. See OAuthConfig. Must be omitted if API is omitted.
+	AccessToken string
+	// Where to look up credentials if Token and AccessToken are empty. Defaults to
+	// $PHABRICATOR_TOKEN (see EnvTokenSource), then ~/.arcrc.
+	TokenSource TokenSource
 	// A LogRus compatible loglevel. Default is "info".
 	LogLevel string
 	// a timeout for the initial endpoint discovery. Defaults to 10 seconds
@@ -238,95 +345,64 @@ type PhabOptions struct {
 	Timeout time.Duration
 	// Where to redirect logger output to. Defaults to os.Stdout
 	Out io.Writer
-	// Alternate file to read from. If nil, will read ~/.arcrc
+	// Alternate file to read from. If nil, will read $ARC_CONFIG or ~/.arcrc
 	Arcrc io.Reader
+	// Check call arguments against the parameters discovered through
+	// conduit.query before sending them. See Phabricator.Validate.
+	ValidateArguments bool
+	// Directory to cache conduit.query responses in. Caching is disabled
+	// if empty. One file per API root is kept.
+	CacheDir string
+	// How long a cached conduit.query response stays valid. Defaults to
+	// 24 hours if empty.
+	CacheTTL time.Duration
+	// Endpoints to register without querying conduit.query at all.
+	// Parameters of such endpoints are unknown, so they're never validated.
+	Endpoints []string
+	// HTTP client used for all requests. Timeout is ignored if set.
+	HTTPClient *http.Client
+	// How many times to retry requests failing with transport errors,
+	// rate limiting or temporary proxy errors. Conduit errors are never
+	// retried. Note that a retried edit may get applied twice if the
+	// response was lost after Phabricator processed it.
+	Retries int
+	// Wait before the first retry, doubled with every further attempt.
+	// Defaults to 1 second if empty.
+	RetryWait time.Duration
+	// Transport of the HTTP client, e.g. a recorder replaying fixtures
+	// in tests. Applies to HTTPClient too, which is copied first.
+	Transport http.RoundTripper
+	// Call user.whoami during initialization and fail with *AuthError
+	// if the credentials are rejected or the account is disabled
+	// or unapproved
+	Preflight bool
 }
 
-type arcrcHost struct {
-	Token string `json:"token"`
-}
-type arcrcConfig struct {
-	Hosts  map[string]arcrcHost `json:"hosts"`
-	Config struct {
-		Default string `json:"default"`
-	} `json:"config"`
-}
-
-func arcConfig(arcrc io.Reader) (*arcrcConfig, error) {
-	if arcrc == nil {
-		whoami, err := user.Current()
-		if err != nil {
-			msg := "Unable to determine current user"
-			logger.Error(msg)
-			return nil, errors.New(msg)
-		}
-
-		arcrcPath := path.Join(whoami.HomeDir, ".arcrc")
-		arcrc, err = os.Open(arcrcPath)
-		if err != nil {
-			msg := "Unable to open ~/.arcrc"
-			logger.Error(msg)
-			return nil, errors.New(msg)
-		}
-	}
-
-	var arcCfg arcrcConfig
-	err := json.NewDecoder(arcrc).Decode(&arcCfg)
-	if err != nil {
-		logger.WithError(err).Error("Unable to parse .arcrc")
+// Endpoints returns the sorted names of all endpoints, discovering them
+// if that didn't happen yet
+func (p *Phabricator) Endpoints(ctx context.Context) ([]string, error) {
+	if err := p.ensureEndpoints(ctx); err != nil {
 		return nil, err
 	}
-	return &arcCfg, nil
-}
-
-func readDefaultAuthFromRC(arcrcFile io.Reader) (string, string, error) {
-	arcCfg, err := arcConfig(arcrcFile)
-	if err != nil {
-		return "", "", err
-	}
-
-	hostURI := arcCfg.Config.Default
-	if hostURI == "" {
-		msg := `Can't determine a default host to connect to. See
-https://www.mediawiki.org/w/index.php?title=Phabricator/Arcanist#Setup for
-details.`
-		logger.Error(msg)
-		return "", "", errors.New(msg)
-	}
-
-	url, err := url.Parse(hostURI)
-	if err != nil {
-		msg := "Unable to parse default Phabricator URI"
-		logger.WithFields(log.Fields{
-			"error": err,
-			"url":   hostURI,
-		}).Error(msg)
-		return "", "", fmt.Errorf("%s: %s", msg, hostURI)
-	}
-	apiPath, _ := url.Parse("/api/")
-	apiURL := url.ResolveReference(apiPath).String()
-
-	host, found := arcCfg.Hosts[apiURL]
-	if !found {
-		return "", "", errors.New("No API config found for default Phabricator host")
+	names := make([]string, 0, len(p.apiInfo))
+	for name := range p.apiInfo {
+		names = append(names, name)
 	}
-
-	return apiURL, host.Token, nil
+	sort.Strings(names)
+	return names, nil
 }
 
-func readTokenFromRC(arcrcFile io.Reader, API string) (string, error) {
-	arcCfg, err := arcConfig(arcrcFile)
-	if err != nil {
+// DescribeEndpoint returns the description, parameters and return
+// type of ENDPOINT as conduit.query reported them
+func (p *Phabricator) DescribeEndpoint(ctx context.Context, endpoint string) (string, error) {
+	if err := p.ensureEndpoints(ctx); err != nil {
 		return "", err
 	}
-
-	hostInfo, exists := arcCfg.Hosts[API]
-	if exists {
-		return hostInfo.Token, nil
+	einfo, known := p.apiInfo[endpoint]
+	if !known {
+		return "", fmt.Errorf("Unknown endpoint %s", endpoint)
 	}
-	msg := "No token found in .arcrc for given API endpoint"
-	logger.WithField("endpoint", API).Error(msg)
-	return "", errors.New(msg)
+	return einfo.String(), nil
 }
 
 // ConduitURI returns the root API endpoint that this instance is configured to
@@ -337,35 +413,74 @@ func (p *Phabricator) ConduitURI() string {
 	return p.apiEndpoint.String()
 }
 
-// Init discovers known API endpoints and defines
-// appropriate callback
+// Init configures the instance, discovers known API endpoints
+// and defines appropriate callbacks.
+// A nil OPTS reads everything from ~/.arcrc with default settings.
 func (p *Phabricator) Init(opts *PhabOptions) error {
+	return p.InitContext(context.Background(), opts)
+}
+
+// InitContext is like Init, but endpoint discovery
+// is bound to the given context
+func (p *Phabricator) InitContext(ctx context.Context, opts *PhabOptions) error {
+	if err := p.configure(ctx, opts); err != nil {
+		return err
+	}
+	if opts != nil && opts.Preflight {
+		if err := p.preflight(ctx); err != nil {
+			return err
+		}
+	}
+	return p.ensureEndpoints(ctx)
+}
+
+// configure sets up everything but the endpoints, which are
+// discovered by ensureEndpoints
+func (p *Phabricator) configure(ctx context.Context, opts *PhabOptions) error {
+	if opts == nil {
+		opts = &PhabOptions{}
+	}
 	loglevel := "info"
 	timeout := 10 * time.Second
-	var arcrcFile io.Reader
-	if opts != nil {
-		if opts.LogLevel != "" {
-			loglevel = opts.LogLevel
-		}
-		if opts.Timeout > 0 {
-			timeout = opts.Timeout
-		} else if opts.Timeout < 0 {
-			return errors.New("Negative timeout specified")
-		}
-		if opts.Out != nil {
-			logger.SetOutput(opts.Out)
-		}
-		if opts.Token != "" && opts.API == "" {
-			msg := "Token specified without an API endpoint"
-			logger.Error(msg)
-			return errors.New(msg)
-		}
-		if opts.Arcrc != nil {
-			arcrcFile = opts.Arcrc
-		}
-		p.apiToken = opts.Token
+	api := opts.API
+	if opts.LogLevel != "" {
+		loglevel = opts.LogLevel
+	}
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	} else if opts.Timeout < 0 {
+		return errors.New("Negative timeout specified")
+	}
+	if opts.Out != nil {
+		logger.SetOutput(opts.Out)
+	}
+	if (opts.Token != "" || opts.AccessToken != "") && api == "" {
+		msg := "Token specified without an API endpoint"
+		logger.Error(msg)
+		return errors.New(msg)
+	}
+	p.cacheTTL = defaultCacheTTL
+	if opts.CacheTTL > 0 {
+		p.cacheTTL = opts.CacheTTL
+	}
+	p.validate = opts.ValidateArguments
+	p.retries = opts.Retries
+	p.retryWait = defaultRetryWait
+	if opts.RetryWait > 0 {
+		p.retryWait = opts.RetryWait
+	}
+	p.cacheDir = opts.CacheDir
+	p.staticEndpoints = opts.Endpoints
+	if opts.HTTPClient != nil {
+		p.client = opts.HTTPClient
+	} else {
+		p.client = &http.Client{Timeout: timeout}
+	}
+	if opts.Transport != nil {
+		client := *p.client
+		client.Transport = opts.Transport
+		p.client = &client
 	}
-	p.client = &http.Client{Timeout: timeout}
 
 	level, err := log.ParseLevel(loglevel)
 	if err != nil {
@@ -376,38 +491,105 @@ func (p *Phabricator) Init(opts *PhabOptions) error {
 	logger.SetReportCaller(true)
 
 	logger.WithFields(log.Fields{
-		"url":      opts.API,
+		"url":      api,
 		"loglevel": loglevel,
 	}).Info("Initializing a Phabricator instance")
 
-	if p.apiToken == "" {
-		if opts.API == "" {
-			if opts.API, p.apiToken, err = readDefaultAuthFromRC(arcrcFile); err != nil {
-				return err
-			}
-		} else {
-			if p.apiToken, err = readTokenFromRC(arcrcFile, opts.API); err != nil {
-				return err
-			}
+	tokenSource := opts.TokenSource
+	if tokenSource == nil || opts.Token != "" || opts.AccessToken != "" {
+		if tokenSource, err = defaultTokenSource(opts); err != nil {
+			return err
 		}
 	}
+	creds, err := tokenSource.Credentials(ctx, api)
+	if err != nil {
+		logger.WithError(err).Error("Unable to find Phabricator credentials")
+		return err
+	}
+	api = creds.API
+	if err := p.setCredentials(creds); err != nil {
+		return err
+	}
 
-	if api, err := url.Parse(opts.API); err != nil {
+	if apiURL, err := url.Parse(api); err != nil {
 		logger.WithFields(log.Fields{
-			"url":   opts.API,
+			"url":   api,
 			"error": err,
 		}).Error("Unable to parse the API URL")
 		return err
 	} else {
-		p.apiEndpoint = api
+		p.apiEndpoint = apiURL
+	}
+	return nil
+}
+
+// ensureEndpoints discovers the endpoints unless that already happened.
+// A failed discovery is retried on the next call.
+func (p *Phabricator) ensureEndpoints(ctx context.Context) error {
+	p.discoveryLock.Lock()
+	defer p.discoveryLock.Unlock()
+	if p.apiInfo != nil {
+		return nil
+	}
+	if p.apiEndpoint == nil {
+		msg := "Phabricator instance is not initialized"
+		logger.Error(msg)
+		return errors.New(msg)
+	}
+	if err := p.ensureSession(ctx); err != nil {
+		return err
 	}
 
-	if ep, err := p.queryEndpoints(); err != nil {
+	var endpoints map[string]endpointInfo
+	if len(p.staticEndpoints) > 0 {
+		logger.WithField("endpoints", p.staticEndpoints).Debug("Skipping endpoint discovery")
+		endpoints = staticEndpoints(p.staticEndpoints)
+	} else if cached, hit := p.cachedEndpoints(); hit {
+		endpoints = cached
+	} else if ep, err := p.queryEndpoints(ctx); err != nil {
 		return err
 	} else {
-		p.apiInfo = ep
+		endpoints = ep
+		if p.cacheDir != "" {
+			if err := writeEndpointCache(p.cacheDir, p.ConduitURI(), ep); err != nil {
+				logger.WithError(err).Warn("Unable to cache endpoints")
+			}
+		}
 	}
 
-	p.loadEndpoints(p.apiInfo)
+	p.loadEndpoints(endpoints)
+	p.apiInfo = endpoints
 	return nil
 }
+
+// setCredentials picks the authentication method, preferring
+// OAuth access tokens, then API tokens and finally legacy certificates
+func (p *Phabricator) setCredentials(creds *Credentials) error {
+	if creds.AccessToken != "" {
+		registerSecret(creds.AccessToken)
+		p.accessToken = creds.AccessToken
+		return nil
+	}
+	if creds.Token != "" {
+		registerSecret(creds.Token)
+		p.apiToken = creds.Token
+		return nil
+	}
+	if creds.Certificate != "" && creds.User != "" {
+		registerSecret(creds.Certificate)
+		logger.WithField("user", creds.User).Debug("Using certificate authentication")
+		p.certUser = creds.User
+		p.certificate = creds.Certificate
+		return nil
+	}
+	msg := "No token or certificate found for given API endpoint"
+	logger.WithField("endpoint", creds.API).Error(msg)
+	return errors.New(msg)
+}
+
+func (p *Phabricator) cachedEndpoints() (map[string]endpointInfo, bool) {
+	if p.cacheDir == "" {
+		return nil, false
+	}
+	return readEndpointCache(p.cacheDir, p.ConduitURI(), p.cacheTTL)
+}
@@ -6,12 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"strings"
 
 	structs "github.com/fatih/structs"
 
 	// https://github.com/Sirupsen/logrus
 	log "github.com/sirupsen/logrus"
+
+	phabTypes "go.showmax.cc/phabricator/types"
 )
 
 type editEndpointCallback func(ctx context.Context, endpoint string, einfo endpointInfo, arguments *EditArguments) error
@@ -37,6 +38,9 @@ type EditArguments struct {
 }
 
 func (p *Phabricator) CallEdit(ctx context.Context, endpoint string, arguments *EditArguments) error {
+	if err := p.ensureEndpoints(ctx); err != nil {
+		return err
+	}
 	handler, defined := p.editEndpoints[endpoint]
 	if !defined {
 		errMsg := "No callback defined for endpoint"
@@ -44,37 +48,55 @@ func (p *Phabricator) CallEdit(ctx context.Context, endpoint string, arguments *
 		logger.WithFields(log.Fields{
 			"endpoint": endpoint,
 		}).Error(errMsg)
-		return nil
+		return fmt.Errorf("%s %s", errMsg, endpoint)
 	}
 	return handler(ctx, endpoint, p.apiInfo[endpoint], arguments)
 }
 
+// editArgsToValues encodes ARGUMENTS as form values. List values,
+// e.g. of projects.add, are sent as transactions[0][value][0] etc.
+func editArgsToValues(arguments *EditArguments) url.Values {
+	values := url.Values{}
+	if arguments.ObjectIdentifier != nil {
+		values.Set("objectIdentifier", fmt.Sprintf("%v", arguments.ObjectIdentifier))
+	}
+	for index, tx := range arguments.Transactions {
+		values.Set(fmt.Sprintf("transactions[%d][type]", index), tx.Type)
+		switch value := tx.Value.(type) {
+		case []string:
+			for i, item := range value {
+				values.Set(fmt.Sprintf("transactions[%d][value][%d]", index, i), item)
+			}
+		case []phabTypes.PHID:
+			for i, item := range value {
+				values.Set(fmt.Sprintf("transactions[%d][value][%d]", index, i), string(item))
+			}
+		default:
+			values.Set(fmt.Sprintf("transactions[%d][value]", index), fmt.Sprintf("%v", tx.Value))
+		}
+	}
+	return values
+}
+
 func editArgsToPost(arguments *EditArguments) (string, error) {
-	var builder strings.Builder
 	switch arguments.ObjectIdentifier.(type) {
-	case int:
-		builder.WriteString(fmt.Sprintf("objectIdentifier=%d", arguments.ObjectIdentifier))
-	case string:
-		builder.WriteString(fmt.Sprintf("objectIdentifier=%s", arguments.ObjectIdentifier))
-	case nil:
-		// No objectIdentifier
+	case int, string, phabTypes.PHID, nil:
 	default:
 		return "", errors.New("objectIdentifier has unsupported type")
 	}
-
-	for index, tx := range arguments.Transactions {
-		builder.WriteString(fmt.Sprintf("&transactions[%d][type]=%s", index, tx.Type))
-		builder.WriteString(fmt.Sprintf("&transactions[%d][value]=%v", index, tx.Value))
-	}
-	return builder.String(), nil
+	return editArgsToValues(arguments).Encode(), nil
 }
 
 func (p *Phabricator) editEndpointHandler(ctx context.Context, endpoint string, einfo endpointInfo, arguments *EditArguments) error {
+	if p.validate {
+		if err := p.validateCall(ctx, endpoint, einfo, editArgsToValues(arguments)); err != nil {
+			return err
+		}
+	}
 	queryArgs, err := editArgsToPost(arguments)
 	if err != nil {
 		return err
 	}
-	data := fmt.Sprintf("api.token=%s&%s", p.apiToken, queryArgs)
 	path, _ := url.Parse(endpoint)
 	fullEndpoint := p.apiEndpoint.ResolveReference(path).String()
 
@@ -82,7 +104,7 @@ func (p *Phabricator) editEndpointHandler(ctx context.Context, endpoint string,
 		"endpoint":   fullEndpoint,
 		"query_args": queryArgs,
 	}).Debug("Sending request")
-	body, err := p.postRequest(ctx, fullEndpoint, data)
+	body, err := p.postRequest(ctx, fullEndpoint, queryArgs)
 	if err != nil {
 		logger.WithFields(log.Fields{
 			"error":     err,
@@ -102,7 +124,7 @@ func (p *Phabricator) editEndpointHandler(ctx context.Context, endpoint string,
 			"PhabricatorErrorCode": baseResp.ErrorCode,
 			"PhabricatorErrorInfo": baseResp.ErrorInfo,
 		}).Error("Invalid Phabricator Request")
-		return fmt.Errorf("[%s] %s", baseResp.ErrorCode, baseResp.ErrorInfo)
+		return conduitError(baseResp.ErrorCode, baseResp.ErrorInfo)
 	}
 	logger.WithFields(structs.Map(baseResp)).Debug("Response")
 	return nil
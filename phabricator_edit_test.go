@@ -0,0 +1,39 @@
+package phabricator
+
+import (
+	"net/url"
+	"testing"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func TestEditArgsToPost(t *testing.T) {
+	args := &EditArguments{
+		ObjectIdentifier: "T1",
+		Transactions: []PhabTransaction{
+			NewTransaction("comment", "Fixed & deployed, 100%"),
+			NewTransaction("projects.add", []string{"PHID-PROJ-1", "PHID-PROJ-2"}),
+		},
+	}
+	encoded, err := editArgsToPost(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment := values.Get("transactions[0][value]"); comment != "Fixed & deployed, 100%" {
+		t.Errorf("Comment mangled: %q", comment)
+	}
+	if values.Get("transactions[1][value][1]") != "PHID-PROJ-2" {
+		t.Errorf("List value not indexed: %s", encoded)
+	}
+	encoded, err = editArgsToPost(&EditArguments{ObjectIdentifier: phabTypes.PHID("PHID-TASK-1")})
+	if err != nil || encoded != "objectIdentifier=PHID-TASK-1" {
+		t.Errorf("PHID objectIdentifier not encoded: %q, %v", encoded, err)
+	}
+	if _, err := editArgsToPost(&EditArguments{ObjectIdentifier: 1.5}); err == nil {
+		t.Error("Unsupported objectIdentifier accepted")
+	}
+}
@@ -36,7 +36,14 @@ type baseSearchResponse struct {
 
 // Call ENDPOINT with ARGUMENTS, using the callback CB to
 // pass results to the caller
+// Endpoints are discovered on the first call, if that didn't happen yet.
 func (p *Phabricator) CallSearch(ctx context.Context, endpoint string, arguments EndpointArguments, typ interface{}) <-chan interface{} {
+	if err := p.ensureEndpoints(ctx); err != nil {
+		resultChan := make(chan interface{}, 1)
+		resultChan <- err
+		close(resultChan)
+		return resultChan
+	}
 	handler, defined := p.searchEndpoints[endpoint]
 	if !defined {
 		errMsg := "No callback defined for endpoint"
@@ -61,10 +68,17 @@ func (p *Phabricator) searchEndpointHandler(ctx context.Context, endpoint string
 			"endpoint": endpoint,
 		}).Error("Failed to encode endpoint query arguments")
 		resultChan <- err
+		close(resultChan)
 		return resultChan
 	}
+	if p.validate {
+		if err := p.validateCall(ctx, endpoint, einfo, queryArgs); err != nil {
+			resultChan <- err
+			close(resultChan)
+			return resultChan
+		}
+	}
 	data := queryArgs.Encode()
-	data = fmt.Sprintf("%s=%s&%s", "api.token", p.apiToken, data)
 	path, _ := url.Parse(endpoint)
 	fullEndpoint := p.apiEndpoint.ResolveReference(path).String()
 	go func() {
@@ -81,42 +95,8 @@ func (p *Phabricator) searchEndpointHandler(ctx context.Context, endpoint string
 			// Fire off the next response as soon as we know the value of "after"
 			// from the previous one
 			after = func(after string) string {
-				postData := data
-				if after != "" {
-					postData = fmt.Sprintf("%s&after=%s", postData, after)
-				}
-
-				body, err := p.postRequest(ctx, fullEndpoint, postData)
+				baseResp, err := p.fetchPage(ctx, endpoint, fullEndpoint, withCursor(data, after))
 				if err != nil {
-					logger.WithFields(log.Fields{
-						"error":     err,
-						"post_data": queryArgs.Encode(),
-						"endpoint":  endpoint,
-						"after":     after,
-					}).Error("Request to Phabricator failed")
-					resultChan <- err
-					return ""
-				}
-				norm, exists := normalization[endpoint]
-				if exists {
-					body = bytes.Replace(body, norm.from, norm.to, -1)
-				}
-				var baseResp baseSearchResponse
-				err = json.Unmarshal(body, &baseResp)
-				if err != nil {
-					logger.WithFields(log.Fields{
-						"error": err,
-					}).Error("Failed to decode JSON")
-					resultChan <- err
-					return ""
-				}
-				if baseResp.ErrorCode != "" {
-					logger.WithFields(log.Fields{
-						"PhabricatorErrorCode": baseResp.ErrorCode,
-						"PhabricatorErrorInfo": baseResp.ErrorInfo,
-					}).Error("Invalid Phabricator Request")
-
-					err := fmt.Errorf("[%s] %s", baseResp.ErrorCode, baseResp.ErrorInfo)
 					resultChan <- err
 					return ""
 				}
@@ -157,3 +137,106 @@ func (p *Phabricator) searchEndpointHandler(ctx context.Context, endpoint string
 	}()
 	return resultChan
 }
+
+// drain discards what's left of RESULTS of CallSearch, so that its
+// goroutines can exit. Cancel the context of the search first,
+// otherwise all remaining pages are still fetched.
+func drain(results <-chan interface{}) {
+	for range results {
+	}
+}
+
+// withCursor adds the pagination cursor AFTER to the encoded arguments DATA
+func withCursor(data, after string) string {
+	if after == "" {
+		return data
+	}
+	return fmt.Sprintf("%s&after=%s", data, url.QueryEscape(after))
+}
+
+// fetchPage requests a single page of search results
+func (p *Phabricator) fetchPage(ctx context.Context, endpoint, fullEndpoint, postData string) (*baseSearchResponse, error) {
+	body, err := p.postRequest(ctx, fullEndpoint, postData)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"error":     err,
+			"post_data": postData,
+			"endpoint":  endpoint,
+		}).Error("Request to Phabricator failed")
+		return nil, err
+	}
+	norm, exists := normalization[endpoint]
+	if exists {
+		body = bytes.Replace(body, norm.from, norm.to, -1)
+	}
+	var baseResp baseSearchResponse
+	err = json.Unmarshal(body, &baseResp)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"error": err,
+		}).Error("Failed to decode JSON")
+		return nil, err
+	}
+	if baseResp.ErrorCode != "" {
+		logger.WithFields(log.Fields{
+			"PhabricatorErrorCode": baseResp.ErrorCode,
+			"PhabricatorErrorInfo": baseResp.ErrorInfo,
+		}).Error("Invalid Phabricator Request")
+		return nil, conduitError(baseResp.ErrorCode, baseResp.ErrorInfo)
+	}
+	return &baseResp, nil
+}
+
+// ResultPage is a single page of search results
+type ResultPage struct {
+	// Results decoded into pointers to the type passed to SearchPage
+	Results []interface{}
+	// Cursor of the next page, empty on the last page
+	After string
+}
+
+// SearchPage fetches the page of ENDPOINT results following the cursor
+// AFTER, or the first page if it's empty. Unlike CallSearch, results
+// keep the order Phabricator returned them in and the cursor is exposed,
+// so a long search can be stored and resumed later.
+func (p *Phabricator) SearchPage(ctx context.Context, endpoint string, arguments EndpointArguments, typ interface{}, after string) (*ResultPage, error) {
+	if err := p.ensureEndpoints(ctx); err != nil {
+		return nil, err
+	}
+	if _, defined := p.searchEndpoints[endpoint]; !defined {
+		msg := "No callback defined for endpoint"
+		logger.WithField("endpoint", endpoint).Error(msg)
+		return nil, fmt.Errorf("%s %s", msg, endpoint)
+	}
+	queryArgs, err := query.Values(arguments)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"error":    err,
+			"endpoint": endpoint,
+		}).Error("Failed to encode endpoint query arguments")
+		return nil, err
+	}
+	if p.validate {
+		if err := p.validateCall(ctx, endpoint, p.apiInfo[endpoint], queryArgs); err != nil {
+			return nil, err
+		}
+	}
+	path, _ := url.Parse(endpoint)
+	fullEndpoint := p.apiEndpoint.ResolveReference(path).String()
+	baseResp, err := p.fetchPage(ctx, endpoint, fullEndpoint, withCursor(queryArgs.Encode(), after))
+	if err != nil {
+		return nil, err
+	}
+
+	typeOf := reflect.TypeOf(typ)
+	page := &ResultPage{After: baseResp.Result.Cursor.After}
+	for _, jsonData := range baseResp.Result.Data {
+		t := reflect.New(typeOf).Interface()
+		if err := json.Unmarshal(jsonData, t); err != nil {
+			logger.WithError(err).Error("Failed to convert JSON to user-supplied type")
+			return nil, err
+		}
+		page.Results = append(page.Results, t)
+	}
+	return page, nil
+}
@@ -0,0 +1,53 @@
+package phabricator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func TestSearchPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.Form.Get("after") {
+		case "":
+			fmt.Fprint(w, `{"result": {"data": [{"id": 3}, {"id": 2}], "cursor": {"after": "2/x"}}}`)
+		case "2/x":
+			fmt.Fprint(w, `{"result": {"data": [{"id": 1}], "cursor": {"after": null}}}`)
+		default:
+			fmt.Fprint(w, `{"error_code": "ERR-CONDUIT-CORE", "error_info": "Bad cursor"}`)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	phab, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-token"), WithLogLevel("panic"),
+		WithEndpoints("maniphest.search"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := phab.SearchPage(ctx, "maniphest.search", phabTypes.TicketSearchArgs{}, phabTypes.Ticket{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Results) != 2 || page.Results[0].(*phabTypes.Ticket).Id != 3 || page.After != "2/x" {
+		t.Errorf("Unexpected first page %+v", page)
+	}
+	page, err = phab.SearchPage(ctx, "maniphest.search", phabTypes.TicketSearchArgs{}, phabTypes.Ticket{}, page.After)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Results) != 1 || page.After != "" {
+		t.Errorf("Unexpected last page %+v", page)
+	}
+	if _, err := phab.SearchPage(ctx, "maniphest.search", phabTypes.TicketSearchArgs{}, phabTypes.Ticket{}, "bogus"); err == nil {
+		t.Error("Expected the Conduit error")
+	}
+	if _, err := phab.SearchPage(ctx, "user.search", phabTypes.UserSearchArgs{}, phabTypes.User{}, ""); err == nil {
+		t.Error("Expected an error for an unknown endpoint")
+	}
+}
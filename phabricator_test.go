@@ -1,6 +1,14 @@
 package phabricator
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -26,3 +34,107 @@ func TestPhabNegativeTimeout(t *testing.T) {
 		t.Error("Initialization didn't catch negative timeout")
 	}
 }
+
+func TestPhabInitNilOptions(t *testing.T) {
+	dir := t.TempDir()
+	arcrc := filepath.Join(dir, "arcrc")
+	if err := os.WriteFile(arcrc, []byte(`{"hosts": {}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ArcUserConfigEnv, arcrc)
+	t.Setenv(ArcSystemConfigEnv, filepath.Join(dir, "arcconfig"))
+	t.Setenv("PHABRICATOR_TOKEN", "")
+	t.Setenv("PHABRICATOR_API", "")
+
+	var phab Phabricator
+	// Without any credentials this fails, but it must not panic
+	err := phab.Init(nil)
+	if !errors.Is(err, ErrNoToken) {
+		t.Errorf("Expected ErrNoToken, got %v", err)
+	}
+}
+
+func TestNewLazyDiscovery(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/api/conduit.query":
+			fmt.Fprint(w, `{"result": {"maniphest.edit": {"params":[]}}}`)
+		case "/api/maniphest.edit":
+			fmt.Fprint(w, `{"result": {"object": {"id": 1, "phid": "PHID-TASK-1"}, "transactions": []}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	phab, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-token"), WithLogLevel("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("New sent %d requests, expected none", n)
+	}
+	args := &EditArguments{
+		ObjectIdentifier: 1,
+		Transactions:     []PhabTransaction{NewTransaction("title", "Lazy")},
+	}
+	if err := phab.CallEdit(ctx, "maniphest.edit", args); err != nil {
+		t.Fatal(err)
+	}
+	if err := phab.CallEdit(ctx, "maniphest.edit", args); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("Expected one discovery and two edits, got %d requests", n)
+	}
+}
+
+func TestNewRejectsTokenWithoutAPI(t *testing.T) {
+	if _, err := New(context.Background(), WithToken("api-token")); err == nil {
+		t.Error("New accepted a token without an API endpoint")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1, 2:
+			http.Error(w, "Try again", http.StatusServiceUnavailable)
+		case 3:
+			fmt.Fprint(w, `{"error_code": "ERR-CONDUIT-CORE", "error_info": "Broken"}`)
+		default:
+			fmt.Fprint(w, `{"result": {"phid": "PHID-USER-1"}}`)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	phab, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-token"), WithLogLevel("error"),
+		WithRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = phab.WhoAmI(ctx)
+	var conduitErr *ConduitError
+	if !errors.As(err, &conduitErr) {
+		t.Errorf("Expected the Conduit error after two retries, got %v", err)
+	}
+	if _, err := phab.WhoAmI(ctx); err != nil {
+		t.Errorf("Conduit error retried or result lost: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Errorf("Expected 4 requests, got %d", n)
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	phab, _ = New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-token"), WithLogLevel("error"))
+	_, err = phab.WhoAmI(ctx)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected *HTTPError without retries, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package phabsync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Schema of the mirror. Every table keeps the whole object as JSON in
+// the data column, so fields without a column of their own can still
+// be queried, e.g. with json_extract(data, '$.fields.points').
+const Schema = `
+CREATE TABLE IF NOT EXISTS tasks (
+	id INTEGER PRIMARY KEY,
+	phid TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	status TEXT NOT NULL,
+	priority INTEGER NOT NULL,
+	author_phid TEXT,
+	owner_phid TEXT,
+	closer_phid TEXT,
+	date_created INTEGER NOT NULL,
+	date_modified INTEGER NOT NULL,
+	date_closed INTEGER,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS task_projects (
+	task_phid TEXT NOT NULL,
+	project_phid TEXT NOT NULL,
+	PRIMARY KEY (task_phid, project_phid)
+);
+CREATE TABLE IF NOT EXISTS revisions (
+	id INTEGER PRIMARY KEY,
+	phid TEXT NOT NULL UNIQUE,
+	title TEXT NOT NULL,
+	status TEXT NOT NULL,
+	author_phid TEXT,
+	repository_phid TEXT,
+	diff_phid TEXT,
+	date_created INTEGER NOT NULL,
+	date_modified INTEGER NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS revision_reviewers (
+	revision_phid TEXT NOT NULL,
+	reviewer_phid TEXT NOT NULL,
+	status TEXT NOT NULL,
+	PRIMARY KEY (revision_phid, reviewer_phid)
+);
+CREATE TABLE IF NOT EXISTS projects (
+	id INTEGER PRIMARY KEY,
+	phid TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	slug TEXT,
+	parent_phid TEXT,
+	milestone INTEGER,
+	depth INTEGER NOT NULL,
+	date_created INTEGER NOT NULL,
+	date_modified INTEGER NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY,
+	phid TEXT NOT NULL UNIQUE,
+	username TEXT NOT NULL,
+	real_name TEXT,
+	roles TEXT,
+	date_created INTEGER NOT NULL,
+	date_modified INTEGER NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS repositories (
+	id INTEGER PRIMARY KEY,
+	phid TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	callsign TEXT,
+	short_name TEXT,
+	vcs TEXT,
+	status TEXT,
+	date_created INTEGER NOT NULL,
+	date_modified INTEGER NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS transactions (
+	phid TEXT PRIMARY KEY,
+	-- Numbered per application, tasks and revisions share IDs
+	id INTEGER NOT NULL,
+	object_phid TEXT NOT NULL,
+	type TEXT,
+	author_phid TEXT,
+	comment TEXT,
+	old_value TEXT,
+	new_value TEXT,
+	date_created INTEGER NOT NULL,
+	date_modified INTEGER NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS transactions_object ON transactions (object_phid, date_created);
+CREATE TABLE IF NOT EXISTS sync_state (
+	tbl TEXT PRIMARY KEY,
+	modified INTEGER NOT NULL,
+	synced INTEGER NOT NULL
+);
+`
+
+// addedColumns were added to tables after they were first released.
+// CREATE TABLE IF NOT EXISTS leaves existing tables alone, so
+// Migrate adds them to mirrors created before.
+var addedColumns = []struct {
+	table, column, definition string
+}{
+	{"transactions", "comment", "TEXT"},
+	{"transactions", "old_value", "TEXT"},
+	{"transactions", "new_value", "TEXT"},
+}
+
+// Migrate creates the tables of Schema that don't exist yet and
+// adds missing columns to existing ones
+func Migrate(ctx context.Context, db *sql.DB) error {
+	for _, statement := range strings.Split(Schema, ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if err := rekeyTransactions(ctx, db); err != nil {
+		return err
+	}
+	for _, added := range addedColumns {
+		columns, err := tableColumns(ctx, db, added.table)
+		if err != nil {
+			return err
+		}
+		if columns[added.column] {
+			continue
+		}
+		statement := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", added.table, added.column, added.definition)
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rekeyTransactions rebuilds a transactions table keyed on id, as the
+// first schema created it. Transactions of different applications
+// overwrote each other there, so the history is synced again.
+func rekeyTransactions(ctx context.Context, db *sql.DB) error {
+	var pk string
+	err := db.QueryRowContext(ctx, "SELECT name FROM pragma_table_info('transactions') WHERE pk = 1").Scan(&pk)
+	if err != nil || pk == "phid" {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, statement := range []string{
+		"DROP TABLE transactions",
+		"DELETE FROM sync_state WHERE tbl IN ('tasks', 'revisions')",
+	} {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return Migrate(ctx, db)
+}
+
+// tableColumns returns the names of the columns of TABLE
+func tableColumns(ctx context.Context, db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
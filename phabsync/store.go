@@ -0,0 +1,145 @@
+package phabsync
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// replace inserts a row of VALUES into the COLUMNS of TABLE,
+// replacing the row with the same key
+func replace(ctx context.Context, tx *sql.Tx, table string, columns []string, values ...interface{}) error {
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	statement := fmt.Sprintf("INSERT OR REPLACE INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), marks)
+	_, err := tx.ExecContext(ctx, statement, values...)
+	return err
+}
+
+// nullable stores empty strings, e.g. PHIDs of unassigned owners, as NULL
+func nullable(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func encode(object interface{}) (string, error) {
+	data, err := json.Marshal(object)
+	return string(data), err
+}
+
+func storeTask(ctx context.Context, tx *sql.Tx, object interface{}) error {
+	task := object.(*phabTypes.Ticket)
+	data, err := encode(task)
+	if err != nil {
+		return err
+	}
+	f := task.Fields
+	err = replace(ctx, tx, "tasks",
+		[]string{"id", "phid", "name", "status", "priority", "author_phid", "owner_phid", "closer_phid",
+			"date_created", "date_modified", "date_closed", "data"},
+		task.Id, task.Phid, f.Name, string(f.Status.Value), int(f.Priority.Value), nullable(f.AuthorPHID),
+		nullable(f.OwnerPHID), nullable(f.CloserPHID), f.DateCreated, f.DateModified, f.DateClosed, data)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM task_projects WHERE task_phid = ?", task.Phid); err != nil {
+		return err
+	}
+	for _, projectPHID := range task.Attachments.Projects.ProjectPHIDs {
+		if err := replace(ctx, tx, "task_projects", []string{"task_phid", "project_phid"}, task.Phid, projectPHID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func storeRevision(ctx context.Context, tx *sql.Tx, object interface{}) error {
+	revision := object.(*phabTypes.Revision)
+	data, err := encode(revision)
+	if err != nil {
+		return err
+	}
+	f := revision.Fields
+	err = replace(ctx, tx, "revisions",
+		[]string{"id", "phid", "title", "status", "author_phid", "repository_phid", "diff_phid",
+			"date_created", "date_modified", "data"},
+		revision.Id, revision.Phid, f.Title, string(f.Status.Value), nullable(f.AuthorPHID),
+		nullable(f.RepositoryPHID), nullable(f.DiffPHID), f.DateCreated, f.DateModified, data)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM revision_reviewers WHERE revision_phid = ?", revision.Phid); err != nil {
+		return err
+	}
+	for _, reviewer := range revision.Attachments.Reviewers.Reviewers {
+		err := replace(ctx, tx, "revision_reviewers", []string{"revision_phid", "reviewer_phid", "status"},
+			revision.Phid, reviewer.ReviewerPHID, reviewer.Status)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func storeProject(ctx context.Context, tx *sql.Tx, object interface{}) error {
+	proj := object.(*phabTypes.Project)
+	data, err := encode(proj)
+	if err != nil {
+		return err
+	}
+	f := proj.Fields
+	var milestone interface{}
+	if f.Milestone != 0 {
+		milestone = f.Milestone
+	}
+	return replace(ctx, tx, "projects",
+		[]string{"id", "phid", "name", "slug", "parent_phid", "milestone", "depth",
+			"date_created", "date_modified", "data"},
+		proj.Id, proj.Phid, f.Name, nullable(f.Slug), nullable(f.Parent.Phid), milestone, f.Depth,
+		f.DateCreated, f.DateModified, data)
+}
+
+func storeUser(ctx context.Context, tx *sql.Tx, object interface{}) error {
+	u := object.(*phabTypes.User)
+	data, err := encode(u)
+	if err != nil {
+		return err
+	}
+	f := u.Fields
+	return replace(ctx, tx, "users",
+		[]string{"id", "phid", "username", "real_name", "roles", "date_created", "date_modified", "data"},
+		u.Id, u.Phid, f.Username, nullable(f.RealName), strings.Join(f.Roles, ","),
+		f.DateCreated, f.DateModified, data)
+}
+
+func storeRepository(ctx context.Context, tx *sql.Tx, object interface{}) error {
+	repo := object.(*phabTypes.Repository)
+	data, err := encode(repo)
+	if err != nil {
+		return err
+	}
+	f := repo.Fields
+	return replace(ctx, tx, "repositories",
+		[]string{"id", "phid", "name", "callsign", "short_name", "vcs", "status",
+			"date_created", "date_modified", "data"},
+		repo.Id, repo.Phid, f.Name, nullable(f.Callsign), nullable(f.ShortName), f.Vcs, f.Status,
+		f.DateCreated, f.DateModified, data)
+}
+
+func storeTransaction(ctx context.Context, tx *sql.Tx, change *phabTypes.Transaction) error {
+	data, err := encode(change)
+	if err != nil {
+		return err
+	}
+	return replace(ctx, tx, "transactions",
+		[]string{"phid", "id", "object_phid", "type", "author_phid", "comment", "old_value", "new_value",
+			"date_created", "date_modified", "data"},
+		change.Phid, change.Id, change.ObjectPHID, nullable(string(change.Type)), nullable(change.AuthorPHID),
+		nullable(change.Comment()), nullable(change.Fields.OldText()), nullable(change.Fields.NewText()),
+		change.DateCreated, change.DateModified, data)
+}
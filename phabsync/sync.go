@@ -0,0 +1,210 @@
+// Package phabsync mirrors tasks, revisions, projects, users and
+// repositories into a local SQL database, so reports can run SQL
+// instead of hammering Conduit. Tasks, revisions and users are synced
+// incrementally with modifiedStart constraints, together with the
+// transaction history of every changed task and revision.
+//
+// The schema is written for SQLite, the package works with any
+// database/sql driver of it:
+//
+//	db, err := sql.Open("sqlite3", "phabricator.db")
+//	...
+//	if err := phabsync.Migrate(ctx, db); err != nil {
+//		...
+//	}
+//	stats, err := phabsync.New(phab, db).Sync(ctx)
+package phabsync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.showmax.cc/phabricator"
+	"go.showmax.cc/phabricator/differential"
+	"go.showmax.cc/phabricator/diffusion"
+	"go.showmax.cc/phabricator/maniphest"
+	"go.showmax.cc/phabricator/project"
+	"go.showmax.cc/phabricator/transaction"
+	phabTypes "go.showmax.cc/phabricator/types"
+	"go.showmax.cc/phabricator/user"
+)
+
+// Tables synced by default, in the order they're synced
+var Tables = []string{"projects", "users", "repositories", "tasks", "revisions"}
+
+// Stats counts the rows written by Sync per table
+type Stats map[string]int
+
+// Syncer copies objects from Conduit into DB
+type Syncer struct {
+	Searcher phabricator.Searcher
+	DB       *sql.DB
+	// Tables to sync, all of Tables if empty
+	Tables []string
+	// Sync the transaction history of changed tasks and revisions
+	History bool
+	// Now is the time syncs are recorded with, time.Now if nil
+	Now func() time.Time
+}
+
+// New creates a Syncer of all tables, including history, into DB.
+// The schema must exist already, see Migrate.
+func New(searcher phabricator.Searcher, db *sql.DB) *Syncer {
+	return &Syncer{Searcher: searcher, DB: db, History: true}
+}
+
+// table describes how to mirror the results of a single search endpoint
+type table struct {
+	endpoint string
+	result   interface{}
+	// args returns the search arguments of objects modified at or after
+	// SINCE, or of all objects if the endpoint can't filter by modification
+	args  func(since time.Time) phabricator.EndpointArguments
+	store func(ctx context.Context, tx *sql.Tx, object interface{}) error
+	// Objects have transactions worth syncing
+	history bool
+}
+
+var tables = map[string]table{
+	"tasks": {maniphest.Endpoint, phabTypes.Ticket{}, func(since time.Time) phabricator.EndpointArguments {
+		return maniphest.Query().ModifiedAfter(since).WithProjects().WithSubscribers().Args()
+	}, storeTask, true},
+	"revisions": {differential.Endpoint, phabTypes.Revision{}, func(since time.Time) phabricator.EndpointArguments {
+		return differential.Query().ModifiedAfter(since).WithReviewers().WithProjects().WithSubscribers().Args()
+	}, storeRevision, true},
+	"projects": {project.Endpoint, phabTypes.Project{}, func(time.Time) phabricator.EndpointArguments {
+		return project.Query().WithMembers().Args()
+	}, storeProject, false},
+	"users": {user.Endpoint, phabTypes.User{}, func(since time.Time) phabricator.EndpointArguments {
+		return user.Query().ModifiedAfter(since).Args()
+	}, storeUser, false},
+	"repositories": {diffusion.Endpoint, phabTypes.Repository{}, func(time.Time) phabricator.EndpointArguments {
+		return diffusion.Query().WithURIs().WithProjects().Args()
+	}, storeRepository, false},
+}
+
+func (s *Syncer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Sync copies all objects modified since the last sync. Each page of
+// results is written in a single database transaction and the sync
+// state is only advanced once a table is complete, so an interrupted
+// sync is simply repeated by the next one.
+func (s *Syncer) Sync(ctx context.Context) (Stats, error) {
+	names := s.Tables
+	if len(names) == 0 {
+		names = Tables
+	}
+	stats := Stats{}
+	for _, name := range names {
+		t, known := tables[name]
+		if !known {
+			return stats, fmt.Errorf("Unknown table %q, expected one of %s", name, strings.Join(Tables, ", "))
+		}
+		if err := s.syncTable(ctx, name, t, stats); err != nil {
+			return stats, fmt.Errorf("Sync of %s failed: %w", name, err)
+		}
+	}
+	return stats, nil
+}
+
+func (s *Syncer) syncTable(ctx context.Context, name string, t table, stats Stats) error {
+	var since int64
+	err := s.DB.QueryRowContext(ctx, "SELECT modified FROM sync_state WHERE tbl = ?", name).Scan(&since)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	// The next sync starts where this one started, so objects modified
+	// while paging through the results aren't missed. The first sync
+	// starts at the epoch, which leaves the constraint out.
+	args := t.args(time.Unix(since, 0))
+	started := s.now().Unix()
+	after := ""
+	for {
+		page, err := s.Searcher.SearchPage(ctx, t.endpoint, args, t.result, after)
+		if err != nil {
+			return err
+		}
+		// History is fetched before the database transaction starts,
+		// so that it isn't held open during requests
+		var history []*phabTypes.Transaction
+		if s.History && t.history {
+			for _, object := range page.Results {
+				transactions, err := s.transactions(ctx, objectField(object, "Phid").String())
+				if err != nil {
+					return err
+				}
+				history = append(history, transactions...)
+			}
+		}
+
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, object := range page.Results {
+			if err := t.store(ctx, tx, object); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		for _, change := range history {
+			if err := storeTransaction(ctx, tx, change); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		stats[name] += len(page.Results)
+		if len(history) > 0 {
+			stats["transactions"] += len(history)
+		}
+
+		after = page.After
+		if after == "" {
+			break
+		}
+	}
+	_, err = s.DB.ExecContext(ctx, "INSERT OR REPLACE INTO sync_state (tbl, modified, synced) VALUES (?, ?, ?)",
+		name, started, s.now().Unix())
+	return err
+}
+
+// objectField returns the field of OBJECT at PATH of Go field names
+func objectField(object interface{}, path ...string) reflect.Value {
+	value := reflect.Indirect(reflect.ValueOf(object))
+	for _, name := range path {
+		value = value.FieldByName(name)
+	}
+	return value
+}
+
+// transactions returns the whole history of the object with PHID
+func (s *Syncer) transactions(ctx context.Context, phid string) ([]*phabTypes.Transaction, error) {
+	var transactions []*phabTypes.Transaction
+	args := transaction.Query(phid).Args()
+	after := ""
+	for {
+		page, err := s.Searcher.SearchPage(ctx, transaction.Endpoint, args, phabTypes.Transaction{}, after)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range page.Results {
+			transactions = append(transactions, result.(*phabTypes.Transaction))
+		}
+		if after = page.After; after == "" {
+			return transactions, nil
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package phabsync
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// https://github.com/mattn/go-sqlite3
+	_ "github.com/mattn/go-sqlite3"
+
+	"go.showmax.cc/phabricator"
+	"go.showmax.cc/phabricator/phabtest"
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func openDB(t *testing.T) (*sql.DB, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "phabsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "phabricator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	// Migrations are repeatable
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func testServer(t *testing.T) *phabtest.Server {
+	t.Helper()
+	server := phabtest.NewServer()
+	server.Now = func() time.Time { return time.Unix(5000, 0) }
+
+	var proj phabTypes.Project
+	proj.Fields.Name = "Backend"
+	proj.Fields.Slug = "backend"
+	var u phabTypes.User
+	u.Fields.Username = "alice"
+	u.Fields.DateModified = 1000
+	var repo phabTypes.Repository
+	repo.Fields.Name = "Server"
+	repo.Fields.Callsign = "SRV"
+	var task phabTypes.Ticket
+	task.Fields.Name = "Crash"
+	task.Fields.Status.Value = phabTypes.TicketStatusOpen
+	task.Fields.Priority.Value = phabTypes.TicketPriorityHigh
+	task.Fields.DateModified = 1000
+	task.Attachments.Projects.ProjectPHIDs = []string{"PHID-PROJ-1"}
+	var revision phabTypes.Revision
+	revision.Fields.Title = "Fix the crash"
+	revision.Fields.Status.Value = phabTypes.RevisionStatusNeedsReview
+	revision.Fields.DateModified = 1000
+	revision.Attachments.Reviewers.Reviewers = []phabTypes.RevisionReviewer{{ReviewerPHID: "PHID-USER-2", Status: "added"}}
+	// IDs are assigned in order: PHID-PROJ-1, PHID-USER-2, ..., PHID-DREV-5
+	objects := []struct {
+		search string
+		object interface{}
+	}{
+		{"project.search", proj},
+		{"user.search", u},
+		{"diffusion.repository.search", repo},
+		{"maniphest.search", task},
+		{"differential.revision.search", revision},
+	}
+	for _, o := range objects {
+		if err := server.Add(o.search, o.object); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, object := range []string{"PHID-TASK-4", "PHID-DREV-5"} {
+		var created phabTypes.Transaction
+		created.Type = phabTypes.TransactionTypeCreate
+		created.ObjectPHID = object
+		created.AuthorPHID = "PHID-USER-2"
+		if err := server.Add("transaction.search", created); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return server
+}
+
+func count(t *testing.T, db *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSync(t *testing.T) {
+	db, cleanup := openDB(t)
+	defer cleanup()
+	server := testServer(t)
+	defer server.Close()
+	ctx := context.Background()
+	phab, err := phabricator.New(ctx, phabricator.WithAPI(server.API()), phabricator.WithToken("api-test"),
+		phabricator.WithLogLevel("panic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	syncer := New(phab, db)
+	clock := int64(2000)
+	syncer.Now = func() time.Time { return time.Unix(clock, 0) }
+	stats, err := syncer.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range Tables {
+		if stats[name] != 1 {
+			t.Errorf("Synced %d %s", stats[name], name)
+		}
+	}
+	if stats["transactions"] != 2 {
+		t.Errorf("Synced %d transactions", stats["transactions"])
+	}
+	var name, status string
+	var priority int
+	var owner sql.NullString
+	err = db.QueryRow("SELECT name, status, priority, owner_phid FROM tasks").Scan(&name, &status, &priority, &owner)
+	if err != nil || name != "Crash" || status != "open" || priority != 80 || owner.Valid {
+		t.Errorf("Unexpected task %q %q %d %v (%v)", name, status, priority, owner, err)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM task_projects WHERE project_phid = 'PHID-PROJ-1'"); n != 1 {
+		t.Errorf("Task projects not synced: %d", n)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM revision_reviewers WHERE reviewer_phid = 'PHID-USER-2'"); n != 1 {
+		t.Errorf("Revision reviewers not synced: %d", n)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM transactions t JOIN tasks ON t.object_phid = tasks.phid"); n != 1 {
+		t.Errorf("Task history not synced: %d", n)
+	}
+
+	// Only the edited task is synced again
+	args := &phabricator.EditArguments{
+		ObjectIdentifier: "PHID-TASK-4",
+		Transactions:     []phabricator.PhabTransaction{phabricator.NewTransaction("title", "Crash on start")},
+	}
+	if err := phab.CallEdit(ctx, "maniphest.edit", args); err != nil {
+		t.Fatal(err)
+	}
+	clock = 6000
+	syncer.Tables = []string{"tasks", "revisions", "projects"}
+	if stats, err = syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Projects can't be searched by modification, so they're all synced
+	if stats["tasks"] != 1 || stats["revisions"] != 0 || stats["projects"] != 1 || stats["transactions"] != 2 {
+		t.Errorf("Unexpected incremental sync %v", stats)
+	}
+	var oldValue, newValue string
+	err = db.QueryRow("SELECT old_value, new_value FROM transactions WHERE object_phid = 'PHID-TASK-4' AND type = 'title'").
+		Scan(&oldValue, &newValue)
+	if err != nil || oldValue != "Crash" || newValue != "Crash on start" {
+		t.Errorf("Unexpected title change %q -> %q (%v)", oldValue, newValue, err)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM tasks WHERE name = 'Crash on start' AND date_modified = 5000"); n != 1 {
+		t.Error("Edited task not synced")
+	}
+	// The state is the start of the last sync, not the newest object seen
+	var modified int64
+	db.QueryRow("SELECT modified FROM sync_state WHERE tbl = 'tasks'").Scan(&modified)
+	if modified != 6000 {
+		t.Errorf("Sync state of tasks at %d", modified)
+	}
+	if stats, err = syncer.Sync(ctx); err != nil || stats["tasks"] != 0 {
+		t.Errorf("Unmodified objects synced again %v (%v)", stats, err)
+	}
+
+	syncer.Tables = []string{"bugs"}
+	if _, err := syncer.Sync(ctx); err == nil {
+		t.Error("Expected an unknown table error")
+	}
+}
+
+func TestMigrateAddsColumns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phabsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "phabricator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// The transactions table as the first schema created it
+	_, err = db.Exec(`CREATE TABLE transactions (
+		id INTEGER PRIMARY KEY,
+		phid TEXT NOT NULL UNIQUE,
+		object_phid TEXT NOT NULL,
+		type TEXT,
+		author_phid TEXT,
+		date_created INTEGER NOT NULL,
+		date_modified INTEGER NOT NULL,
+		data TEXT NOT NULL
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	columns, err := tableColumns(ctx, db, "transactions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range []string{"comment", "old_value", "new_value"} {
+		if !columns[column] {
+			t.Errorf("Column %s not added", column)
+		}
+	}
+	var pk string
+	db.QueryRow("SELECT name FROM pragma_table_info('transactions') WHERE pk = 1").Scan(&pk)
+	if pk != "phid" {
+		t.Errorf("Transactions keyed on %q", pk)
+	}
+}
+
+func TestTransactionsSharingIDs(t *testing.T) {
+	db, cleanup := openDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Maniphest and Differential number their transactions separately
+	for _, object := range []string{"PHID-TASK-1", "PHID-DREV-1"} {
+		var change phabTypes.Transaction
+		change.Id = 7
+		change.Phid = "PHID-XACT-" + object[5:9] + "-7"
+		change.ObjectPHID = object
+		if err := storeTransaction(ctx, tx, &change); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM transactions WHERE id = 7"); n != 2 {
+		t.Errorf("Expected both transactions with ID 7, got %d", n)
+	}
+}
@@ -0,0 +1,268 @@
+package phabtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RecordEnv set to a non-empty value makes DefaultMode record fixtures
+const RecordEnv = "PHABTEST_RECORD"
+
+// Replaced credentials in recorded fixtures
+const scrubbed = "SCRUBBED"
+
+// Mode of a Recorder
+type Mode int
+
+const (
+	// Replay answers requests from the fixture file without any network access
+	Replay Mode = iota
+	// Record sends requests to the real server and saves them to the fixture file
+	Record
+)
+
+// DefaultMode records if $PHABTEST_RECORD is set and replays otherwise,
+// so fixtures can be refreshed by running the tests against a real instance
+func DefaultMode() Mode {
+	if os.Getenv(RecordEnv) != "" {
+		return Record
+	}
+	return Replay
+}
+
+// Interaction is a single recorded Conduit call
+type Interaction struct {
+	// Conduit method, e.g. maniphest.search
+	Method string `json:"method"`
+	// Form parameters with credentials scrubbed
+	Params url.Values `json:"params"`
+	Status int        `json:"status"`
+	// Raw response body
+	Response string `json:"response"`
+}
+
+// Fixture is the content of a fixture file
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper recording Conduit calls into a fixture
+// file or replaying them from it. Pass it to phabricator.WithTransport.
+//
+// Requests are matched by the method and parameters, ignoring their
+// order, credentials and the volatile parameters in Ignore. Recorded
+// interactions are replayed in order, so the pages of a search that
+// differ only in the after cursor are answered one after another.
+type Recorder struct {
+	Path string
+	Mode Mode
+	// Transport of the real requests when recording, http.DefaultTransport if nil
+	Transport http.RoundTripper
+	// Parameters ignored when matching, after and before by default
+	Ignore []string
+
+	lock    sync.Mutex
+	fixture Fixture
+	used    []bool
+}
+
+// NewRecorder creates a recorder of the fixture file at PATH.
+// Replaying recorders read the file right away.
+func NewRecorder(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{Path: path, Mode: mode, Ignore: []string{"after", "before"}}
+	if mode == Record {
+		return r, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.fixture); err != nil {
+		return nil, fmt.Errorf("Invalid fixture %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.fixture.Interactions))
+	return r, nil
+}
+
+// Save writes the recorded interactions to the fixture file.
+// Replaying recorders don't write anything.
+func (r *Recorder) Save() error {
+	if r.Mode != Record {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	data, err := json.MarshalIndent(r.fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.Path, append(data, '\n'), 0644)
+}
+
+// connectMethod is the Conduit method establishing a certificate session
+const connectMethod = "conduit.connect"
+
+// isCredential tells whether the parameter KEY of METHOD carries a secret.
+// The params of conduit.connect hold the signed certificate token.
+func isCredential(method, key string) bool {
+	return key == "api.token" || key == "access_token" || key == "api.sessionKey" || key == "api.connectionID" ||
+		strings.HasPrefix(key, "__conduit__") ||
+		(method == connectMethod && key == "params")
+}
+
+// scrubSession replaces the session key and connection ID in the
+// response BODY of conduit.connect. Anything that isn't a successful
+// response is returned as is.
+func scrubSession(body []byte) []byte {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return body
+	}
+	var session map[string]interface{}
+	if err := json.Unmarshal(response["result"], &session); err != nil || session == nil {
+		return body
+	}
+	session["sessionKey"] = scrubbed
+	session["connectionID"] = 0
+	result, err := json.Marshal(session)
+	if err != nil {
+		return body
+	}
+	response["result"] = result
+	scrubbedBody, err := json.Marshal(response)
+	if err != nil {
+		return body
+	}
+	return scrubbedBody
+}
+
+// RoundTrip records or replays a single request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	params, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	method := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+
+	if r.Mode == Record {
+		return r.record(req, method, body, params)
+	}
+	return r.replay(req, method, params)
+}
+
+func (r *Recorder) record(req *http.Request, method string, body []byte, params url.Values) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	outgoing := req.Clone(req.Context())
+	outgoing.Body = ioutil.NopCloser(bytes.NewReader(body))
+	outgoing.ContentLength = int64(len(body))
+	resp, err := transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Credentials are removed from the parameters, and from responses
+	// in case the server echoes them. Sessions are credentials too.
+	recorded := string(respBody)
+	if method == connectMethod {
+		recorded = string(scrubSession(respBody))
+	}
+	for key, values := range params {
+		if !isCredential(method, key) {
+			continue
+		}
+		for _, value := range values {
+			if value != "" {
+				recorded = strings.Replace(recorded, value, scrubbed, -1)
+			}
+		}
+		params[key] = []string{scrubbed}
+	}
+	r.lock.Lock()
+	r.fixture.Interactions = append(r.fixture.Interactions, Interaction{
+		Method:   method,
+		Params:   params,
+		Status:   resp.StatusCode,
+		Response: recorded,
+	})
+	r.lock.Unlock()
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, method string, params url.Values) (*http.Response, error) {
+	wanted := r.normalize(method, params)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, interaction := range r.fixture.Interactions {
+		if r.used[i] || interaction.Method != method || !reflect.DeepEqual(r.normalize(method, interaction.Params), wanted) {
+			continue
+		}
+		r.used[i] = true
+		status := interaction.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          ioutil.NopCloser(strings.NewReader(interaction.Response)),
+			ContentLength: int64(len(interaction.Response)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("No recorded interaction left for %s with %s in %s", method, wanted.Encode(), r.Path)
+}
+
+// normalize drops credentials and ignored parameters and sorts values,
+// so requests differing only in those match
+func (r *Recorder) normalize(method string, params url.Values) url.Values {
+	normalized := make(url.Values, len(params))
+	for key, values := range params {
+		if isCredential(method, key) || r.ignored(key) {
+			continue
+		}
+		sorted := append([]string(nil), values...)
+		sort.Strings(sorted)
+		normalized[key] = sorted
+	}
+	return normalized
+}
+
+func (r *Recorder) ignored(key string) bool {
+	for _, ignored := range r.Ignore {
+		if key == ignored {
+			return true
+		}
+	}
+	return false
+}
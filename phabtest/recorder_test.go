@@ -0,0 +1,166 @@
+package phabtest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.showmax.cc/phabricator"
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phabtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tickets.json")
+
+	server := NewServer()
+	server.PageSize = 1
+	server.Add("maniphest.search", newTicket("First", "open"), newTicket("Second", "open"))
+
+	ctx := context.Background()
+	recorder, err := NewRecorder(path, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phab, err := phabricator.New(ctx,
+		phabricator.WithAPI(server.API()),
+		phabricator.WithToken("api-recordedsecret"),
+		phabricator.WithTransport(recorder),
+		phabricator.WithLogLevel("panic"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := searchTickets(ctx, phab, phabTypes.TicketSearchArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "recordedsecret") {
+		t.Errorf("Token not scrubbed from the fixture:\n%s", data)
+	}
+
+	recorder, err = NewRecorder(path, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phab, err = phabricator.New(ctx,
+		phabricator.WithAPI(server.API()),
+		phabricator.WithToken("api-anothertoken"),
+		phabricator.WithTransport(recorder),
+		phabricator.WithLogLevel("panic"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := searchTickets(ctx, phab, phabTypes.TicketSearchArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pages are decoded concurrently, so results may come in any order
+	names := make(map[string]bool)
+	for _, ticket := range recorded {
+		names[ticket.Fields.Name] = true
+	}
+	for _, ticket := range replayed {
+		delete(names, ticket.Fields.Name)
+	}
+	if len(replayed) != 2 || len(names) != 0 {
+		t.Errorf("Replayed %v, recorded %v", replayed, recorded)
+	}
+
+	var args phabTypes.TicketSearchArgs
+	args.Constraints.Ids = []int{1}
+	if _, err := searchTickets(ctx, phab, args); err == nil {
+		t.Error("Unrecorded request answered")
+	}
+}
+
+func TestRecordSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phabtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.json")
+
+	server := NewServer()
+	server.Add("maniphest.search", newTicket("First", "open"))
+	server.Handle("conduit.connect", func(url.Values) (interface{}, error) {
+		return map[string]interface{}{
+			"connectionID": 4711,
+			"sessionKey":   "sessionsecret",
+			"userPHID":     "PHID-USER-alice",
+		}, nil
+	})
+	certificate := phabricator.WithTokenSource(phabricator.TokenSourceFunc(
+		func(ctx context.Context, api string) (*phabricator.Credentials, error) {
+			return &phabricator.Credentials{API: api, User: "alice", Certificate: "certificate"}, nil
+		}))
+
+	ctx := context.Background()
+	recorder, err := NewRecorder(path, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phab, err := phabricator.New(ctx,
+		phabricator.WithAPI(server.API()),
+		certificate,
+		phabricator.WithTransport(recorder),
+		phabricator.WithLogLevel("panic"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := searchTickets(ctx, phab, phabTypes.TicketSearchArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"sessionsecret", "4711", "authSignature"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("%s not scrubbed from the fixture:\n%s", secret, data)
+		}
+	}
+
+	// The signature differs on every connect, replaying must not care
+	recorder, err = NewRecorder(path, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phab, err = phabricator.New(ctx,
+		phabricator.WithAPI(server.API()),
+		certificate,
+		phabricator.WithTransport(recorder),
+		phabricator.WithLogLevel("panic"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tickets, err := searchTickets(ctx, phab, phabTypes.TicketSearchArgs{}); err != nil || len(tickets) != 1 {
+		t.Errorf("Replay failed: %v, %v", tickets, err)
+	}
+}
@@ -0,0 +1,757 @@
+// Package phabtest provides a fake Conduit server for testing code
+// built on the phabricator package without a real Phabricator.
+//
+//	server := phabtest.NewServer()
+//	defer server.Close()
+//	server.Add("maniphest.search", phabTypes.Ticket{})
+//	phab, _ := phabricator.New(ctx, phabricator.WithAPI(server.API()), phabricator.WithToken("api-test"))
+//
+// The server answers conduit.query and user.whoami, pages through
+// objects added with Add on *.search, applies *.edit transactions to
+// them and can be told to fail calls with FailNext. Legacy certificate
+// sessions are established with conduit.connect for Certificates.
+package phabtest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default number of results per page of *.search
+const defaultPageSize = 100
+
+// PHID types of objects returned by the search endpoints
+var phidTypes = map[string]string{
+	"maniphest.search":             "TASK",
+	"user.search":                  "USER",
+	"project.search":               "PROJ",
+	"differential.revision.search": "DREV",
+	"differential.diff.search":     "DIFF",
+	"diffusion.repository.search":  "REPO",
+	transactionSearch:              "XACT",
+}
+
+// transactionSearch holds the history of edited objects
+const transactionSearch = "transaction.search"
+
+// Parameters conduit.query reports for searches, edits and transaction.search
+var (
+	searchParams = map[string]string{
+		"queryKey":    "optional string",
+		"constraints": "optional map<string, wild>",
+		"attachments": "optional map<string, bool>",
+		"order":       "optional order",
+		"before":      "optional string",
+		"after":       "optional string",
+		"limit":       "optional int (default = 100)",
+	}
+	transactionParams = map[string]string{
+		"objectIdentifier": "optional phid|string",
+		"constraints":      "optional map<string, wild>",
+		"before":           "optional string",
+		"after":            "optional string",
+		"limit":            "optional int (default = 100)",
+	}
+	editParams = map[string]string{
+		"transactions":     "list<map<string, wild>>",
+		"objectIdentifier": "optional id|phid|string",
+	}
+)
+
+var transactionKey = regexp.MustCompile(`^transactions\[([0-9]+)\]\[(type|value)\](?:\[[0-9]*\])?$`)
+
+// Object is a search result as JSON would decode it into interface{}
+type Object map[string]interface{}
+
+// Field returns the value at the dotted PATH, e.g. fields.status.value
+func (o Object) Field(path string) interface{} {
+	var value interface{} = map[string]interface{}(o)
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// SetField sets the value at the dotted PATH, creating maps on the way
+func (o Object) SetField(path string, value interface{}) {
+	m := map[string]interface{}(o)
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+// Constraint tells whether OBJECT matches a search constraint with VALUES
+type Constraint func(object Object, values []string) bool
+
+// Handler answers a Conduit call with PARAMS. A returned *Error is
+// reported as a Conduit error, any other error as ERR-CONDUIT-CORE.
+type Handler func(params url.Values) (interface{}, error)
+
+// Error is a Conduit error returned by handlers or injected with FailNext
+type Error struct {
+	Code string
+	Info string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Code, e.Info)
+}
+
+// Call is a request the server received
+type Call struct {
+	Method string
+	Params url.Values
+}
+
+// Transaction is a single applied edit transaction
+type Transaction struct {
+	PHID  string
+	Type  string
+	Value interface{}
+}
+
+// Edit is a *.edit call applied to the store
+type Edit struct {
+	Method       string
+	ObjectPHID   string
+	Transactions []Transaction
+}
+
+// fault is an injected failure of a single call
+type fault struct {
+	err    *Error
+	status int
+}
+
+// Server is a fake Conduit API
+type Server struct {
+	*httptest.Server
+	// Token required from clients, any token is accepted if empty
+	Token string
+	// Certificates of users conduit.connect accepts, by username. If set,
+	// clients without Token must send the session in api.sessionKey and
+	// api.connectionID, like Phabricator reads it.
+	Certificates map[string]string
+	// Results per page of *.search unless the client asks for fewer
+	PageSize int
+	// Now is the time of edits, time.Now if nil
+	Now func() time.Time
+	// Viewer is returned by user.whoami
+	Viewer Object
+
+	lock        sync.Mutex
+	objects     map[string][]Object
+	constraints map[string]map[string]Constraint
+	handlers    map[string]Handler
+	faults      map[string][]fault
+	calls       []Call
+	edits       []Edit
+	lastID      int
+	// Transactions are numbered apart from objects, like in Phabricator
+	lastTransactionID int
+	// Connection IDs of sessions by session key
+	sessions map[string]int64
+}
+
+// NewServer starts a fake Conduit server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		PageSize: defaultPageSize,
+		Viewer: Object{
+			"phid":     "PHID-USER-viewer",
+			"userName": "viewer",
+			"realName": "Test Viewer",
+			"roles":    []interface{}{"verified", "approved", "activated"},
+		},
+		// Every install has the history of edits
+		objects:     map[string][]Object{transactionSearch: nil},
+		constraints: make(map[string]map[string]Constraint),
+		handlers:    make(map[string]Handler),
+		faults:      make(map[string][]fault),
+		sessions:    make(map[string]int64),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// API returns the Conduit URI clients should use
+func (s *Server) API() string {
+	return s.URL + "/api/"
+}
+
+// Add makes SEARCH, e.g. maniphest.search, and the matching edit
+// endpoint available and stores OBJECTS, which are search result
+// structs such as types.Ticket or anything that encodes to JSON alike.
+// Objects without an ID or PHID get new ones.
+func (s *Server) Add(search string, objects ...interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, known := s.objects[search]; !known {
+		s.objects[search] = nil
+	}
+	for _, o := range objects {
+		data, err := json.Marshal(o)
+		if err != nil {
+			return err
+		}
+		var object Object
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+		s.store(search, object)
+	}
+	return nil
+}
+
+// store assigns an ID and PHID to OBJECT if missing and adds it
+func (s *Server) store(search string, object Object) {
+	lastID := &s.lastID
+	if search == transactionSearch {
+		lastID = &s.lastTransactionID
+	}
+	id, _ := object["id"].(float64)
+	if id == 0 {
+		*lastID++
+		id = float64(*lastID)
+		object["id"] = id
+	} else if int(id) > *lastID {
+		*lastID = int(id)
+	}
+	typ, known := phidTypes[search]
+	if !known {
+		typ = "XXXX"
+	}
+	if phid, _ := object["phid"].(string); phid == "" {
+		object["phid"] = fmt.Sprintf("PHID-%s-%d", typ, int(id))
+	}
+	// The type of transactions is the kind of change, not the PHID type
+	if t, _ := object["type"].(string); t == "" && search != transactionSearch {
+		object["type"] = typ
+	}
+	s.objects[search] = append(s.objects[search], object)
+}
+
+// Objects returns the objects stored for SEARCH, including edits
+func (s *Server) Objects(search string) []Object {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Object(nil), s.objects[search]...)
+}
+
+// AddConstraint teaches SEARCH the constraint NAME, e.g. projects.
+// ids, phids and the created/modified ranges are known everywhere,
+// maniphest.search also knows statuses, priorities, assigned and authorPHIDs.
+// Unknown constraints fail the call, like Phabricator does.
+func (s *Server) AddConstraint(search, name string, constraint Constraint) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.constraints[search] == nil {
+		s.constraints[search] = make(map[string]Constraint)
+	}
+	s.constraints[search][name] = constraint
+}
+
+// Handle answers METHOD with HANDLER instead of the built-in behavior
+func (s *Server) Handle(method string, handler Handler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handlers[method] = handler
+}
+
+// FailNext makes the next call of METHOD fail with a Conduit error.
+// Use "*" to fail the next call of any method. Calls queue up.
+func (s *Server) FailNext(method, code, info string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults[method] = append(s.faults[method], fault{err: &Error{Code: code, Info: info}})
+}
+
+// FailNextHTTP makes the next call of METHOD fail with HTTP STATUS
+func (s *Server) FailNextHTTP(method string, status int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults[method] = append(s.faults[method], fault{status: status})
+}
+
+// Calls returns all calls received so far
+func (s *Server) Calls() []Call {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Edits returns all edits applied so far
+func (s *Server) Edits() []Edit {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Edit(nil), s.edits...)
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// nextFault pops the failure injected for METHOD, if any
+func (s *Server) nextFault(method string) *fault {
+	for _, key := range []string{method, "*"} {
+		if queue := s.faults[key]; len(queue) > 0 {
+			s.faults[key] = queue[1:]
+			return &queue[0]
+		}
+	}
+	return nil
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls = append(s.calls, Call{Method: method, Params: r.PostForm})
+
+	if f := s.nextFault(method); f != nil {
+		if f.status != 0 {
+			http.Error(w, http.StatusText(f.status), f.status)
+			return
+		}
+		writeResponse(w, nil, f.err)
+		return
+	}
+	if err := s.authenticate(method, r.PostForm); err != nil {
+		writeResponse(w, nil, err)
+		return
+	}
+	result, err := s.dispatch(method, r.PostForm)
+	writeResponse(w, result, err)
+}
+
+// authenticate checks the token or session PARAMS of METHOD carry,
+// if the server requires any
+func (s *Server) authenticate(method string, params url.Values) error {
+	if s.Token == "" && len(s.Certificates) == 0 {
+		return nil
+	}
+	if s.Token != "" && (params.Get("api.token") == s.Token || params.Get("access_token") == s.Token) {
+		return nil
+	}
+	if len(s.Certificates) == 0 {
+		return &Error{Code: "ERR-INVALID-AUTH", Info: "API token is not valid."}
+	}
+	if method == connectMethod {
+		return nil
+	}
+	connectionID, found := s.sessions[params.Get("api.sessionKey")]
+	if !found || strconv.FormatInt(connectionID, 10) != params.Get("api.connectionID") {
+		return &Error{Code: "ERR-INVALID-SESSION", Info: "Session key is invalid."}
+	}
+	return nil
+}
+
+// connect answers conduit.connect, checking the signature of the
+// certificate the way Phabricator does
+func (s *Server) connect(params url.Values) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(params.Get("params")))
+	decoder.UseNumber()
+	var request struct {
+		User          string      `json:"user"`
+		AuthToken     interface{} `json:"authToken"`
+		AuthSignature string      `json:"authSignature"`
+	}
+	if err := decoder.Decode(&request); err != nil {
+		return nil, &Error{Code: "ERR-CONDUIT-CORE", Info: fmt.Sprintf("Invalid params: %s", err)}
+	}
+	certificate, known := s.Certificates[request.User]
+	if !known {
+		return nil, &Error{Code: "ERR-INVALID-USER", Info: "The username you are attempting to authenticate with is not valid."}
+	}
+	signature := sha1.Sum([]byte(fmt.Sprint(request.AuthToken) + certificate))
+	if hex.EncodeToString(signature[:]) != request.AuthSignature {
+		return nil, &Error{Code: "ERR-INVALID-CERTIFICATE", Info: "Your authentication certificate for this server is invalid."}
+	}
+	connectionID := int64(len(s.sessions) + 1)
+	sessionKey := fmt.Sprintf("session-%d", connectionID)
+	s.sessions[sessionKey] = connectionID
+	return map[string]interface{}{
+		"connectionID": connectionID,
+		"sessionKey":   sessionKey,
+		"userPHID":     s.Viewer["phid"],
+	}, nil
+}
+
+func writeResponse(w http.ResponseWriter, result interface{}, err error) {
+	response := map[string]interface{}{"result": result, "error_code": nil, "error_info": nil}
+	if err != nil {
+		conduitErr, ok := err.(*Error)
+		if !ok {
+			conduitErr = &Error{Code: "ERR-CONDUIT-CORE", Info: err.Error()}
+		}
+		response["result"] = nil
+		response["error_code"] = conduitErr.Code
+		response["error_info"] = conduitErr.Info
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) dispatch(method string, params url.Values) (interface{}, error) {
+	if handler, found := s.handlers[method]; found {
+		return handler(params)
+	}
+	switch {
+	case method == "conduit.query":
+		return s.conduitQuery(), nil
+	case method == "user.whoami":
+		return s.Viewer, nil
+	case method == connectMethod && len(s.Certificates) > 0:
+		return s.connect(params)
+	case strings.HasSuffix(method, ".search"):
+		if _, known := s.objects[method]; known {
+			return s.search(method, params)
+		}
+	case strings.HasSuffix(method, ".edit"):
+		search := strings.TrimSuffix(method, ".edit") + ".search"
+		if _, known := s.objects[search]; known {
+			return s.edit(method, search, params)
+		}
+	}
+	return nil, &Error{Code: "ERR-CONDUIT-CALL", Info: fmt.Sprintf("Conduit method %q does not exist.", method)}
+}
+
+func (s *Server) conduitQuery() map[string]interface{} {
+	endpoints := map[string]interface{}{
+		"conduit.query": map[string]interface{}{"params": map[string]string{}, "return": "dict<dict>"},
+		"user.whoami":   map[string]interface{}{"params": map[string]string{}, "return": "nonempty dict<string, wild>"},
+	}
+	for search := range s.objects {
+		if search == transactionSearch {
+			endpoints[search] = map[string]interface{}{"params": transactionParams, "return": "map<string, wild>"}
+			continue
+		}
+		endpoints[search] = map[string]interface{}{"params": searchParams, "return": "map<string, wild>"}
+		edit := strings.TrimSuffix(search, ".search") + ".edit"
+		endpoints[edit] = map[string]interface{}{"params": editParams, "return": "map<string, wild>"}
+	}
+	for method := range s.handlers {
+		if _, found := endpoints[method]; !found {
+			endpoints[method] = map[string]interface{}{"params": map[string]string{}, "return": "wild"}
+		}
+	}
+	return endpoints
+}
+
+// constraint returns the built-in or added constraint NAME of SEARCH
+func (s *Server) constraint(search, name string) Constraint {
+	if c, found := s.constraints[search][name]; found {
+		return c
+	}
+	switch name {
+	case "ids":
+		return matchField("id")
+	case "phids":
+		return matchField("phid")
+	case "createdStart":
+		return compareField("fields.dateCreated", 1)
+	case "createdEnd":
+		return compareField("fields.dateCreated", -1)
+	case "modifiedStart":
+		return compareField("fields.dateModified", 1)
+	case "modifiedEnd":
+		return compareField("fields.dateModified", -1)
+	}
+	if search == "maniphest.search" {
+		switch name {
+		case "statuses":
+			return matchField("fields.status.value")
+		case "priorities":
+			return matchField("fields.priority.value")
+		case "assigned":
+			return matchField("fields.ownerPHID")
+		case "authorPHIDs":
+			return matchField("fields.authorPHID")
+		}
+	}
+	if search == transactionSearch && name == "authorPHIDs" {
+		return matchField("authorPHID")
+	}
+	return nil
+}
+
+// matchField matches objects whose field at PATH equals any of the values
+func matchField(path string) Constraint {
+	return func(object Object, values []string) bool {
+		field := fmt.Sprint(object.Field(path))
+		if number, ok := object.Field(path).(float64); ok {
+			field = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		for _, value := range values {
+			if value == field {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// compareField matches objects whose numeric field at PATH is at least
+// (SIGN 1) or at most (SIGN -1) the value
+func compareField(path string, sign int) Constraint {
+	return func(object Object, values []string) bool {
+		field, _ := object.Field(path).(float64)
+		limit, _ := strconv.ParseFloat(values[0], 64)
+		return float64(sign)*(field-limit) >= 0
+	}
+}
+
+func (s *Server) search(method string, params url.Values) (interface{}, error) {
+	constraints := make(map[string][]string)
+	for key, values := range params {
+		if !strings.HasPrefix(key, "constraints[") {
+			continue
+		}
+		name := strings.TrimPrefix(key, "constraints[")
+		name = name[:strings.Index(name, "]")]
+		constraints[name] = append(constraints[name], values...)
+	}
+	names := make([]string, 0, len(constraints))
+	for name := range constraints {
+		if s.constraint(method, name) == nil {
+			return nil, &Error{Code: "ERR-CONDUIT-CORE", Info: fmt.Sprintf("Constraint %q is not a valid constraint for this query.", name)}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var objectPHID interface{}
+	if method == transactionSearch {
+		identifier := params.Get("objectIdentifier")
+		if identifier == "" && len(constraints["phids"]) == 0 {
+			return nil, &Error{Code: "ERR-CONDUIT-CORE", Info: "When querying transactions, you must provide an object."}
+		}
+		if identifier != "" {
+			if objectPHID = s.findAny(identifier); objectPHID == nil {
+				return nil, &Error{Code: "ERR-CONDUIT-CORE", Info: fmt.Sprintf("No object %q exists.", identifier)}
+			}
+		}
+	}
+
+	// Newest first, like Phabricator's default order
+	objects := append([]Object(nil), s.objects[method]...)
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i]["id"].(float64) > objects[j]["id"].(float64)
+	})
+
+	limit := s.PageSize
+	if requested, err := strconv.Atoi(params.Get("limit")); err == nil && requested > 0 && requested < limit {
+		limit = requested
+	}
+	after, _ := strconv.ParseFloat(params.Get("after"), 64)
+
+	data := make([]Object, 0, limit)
+	var next interface{}
+	for _, object := range objects {
+		if after != 0 && object["id"].(float64) >= after {
+			continue
+		}
+		matches := objectPHID == nil || object["objectPHID"] == objectPHID
+		for _, name := range names {
+			if !s.constraint(method, name)(object, constraints[name]) {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		if len(data) == limit {
+			next = strconv.Itoa(int(data[len(data)-1]["id"].(float64)))
+			break
+		}
+		data = append(data, object)
+	}
+	return map[string]interface{}{
+		"data":   data,
+		"maps":   map[string]interface{}{},
+		"query":  map[string]interface{}{"queryKey": nil},
+		"cursor": map[string]interface{}{"limit": limit, "after": next, "before": nil, "order": nil},
+	}, nil
+}
+
+// find returns the object of SEARCH with IDENTIFIER: an ID, a PHID
+// or a monogram such as T12
+func (s *Server) find(search, identifier string) Object {
+	trimmed := strings.TrimLeft(identifier, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	for _, object := range s.objects[search] {
+		if object["phid"] == identifier || fmt.Sprint(object["id"]) == trimmed {
+			return object
+		}
+	}
+	return nil
+}
+
+// findAny returns the PHID of the object with IDENTIFIER in any search
+func (s *Server) findAny(identifier string) interface{} {
+	for search := range s.objects {
+		if search == transactionSearch {
+			continue
+		}
+		if object := s.find(search, identifier); object != nil {
+			return object["phid"]
+		}
+	}
+	return nil
+}
+
+// Where maniphest.edit transactions store their value
+var maniphestFields = map[string]string{
+	"title":       "fields.name",
+	"description": "fields.description.raw",
+	"status":      "fields.status.value",
+	"priority":    "fields.priority.name",
+	"owner":       "fields.ownerPHID",
+	"points":      "fields.points",
+	"subtype":     "fields.subtype",
+	"space":       "fields.spacePHID",
+	"view":        "fields.policy.view",
+	"edit":        "fields.policy.edit",
+}
+
+// historyEntry creates the transaction.search result of a change
+// of OBJECT by the viewer
+func (s *Server) historyEntry(object Object, typ string, now float64) Object {
+	return Object{
+		"type":         typ,
+		"objectPHID":   object["phid"],
+		"authorPHID":   s.Viewer["phid"],
+		"dateCreated":  now,
+		"dateModified": now,
+		"groupID":      fmt.Sprintf("%d", len(s.edits)+1),
+		"comments":     []interface{}{},
+		"fields":       map[string]interface{}{},
+	}
+}
+
+func (s *Server) edit(method, search string, params url.Values) (interface{}, error) {
+	var object Object
+	if identifier := params.Get("objectIdentifier"); identifier != "" {
+		if object = s.find(search, identifier); object == nil {
+			return nil, &Error{Code: "ERR-CONDUIT-CORE", Info: fmt.Sprintf("No object exists with ID %q.", identifier)}
+		}
+	}
+
+	// Collect transactions in order, list values may be indexed
+	types := make(map[int]string)
+	values := make(map[int][]string)
+	for key, vals := range params {
+		match := transactionKey.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		index, _ := strconv.Atoi(match[1])
+		if match[2] == "type" {
+			types[index] = vals[0]
+		} else {
+			values[index] = append(values[index], vals...)
+		}
+	}
+	if len(types) == 0 {
+		return nil, &Error{Code: "ERR-CONDUIT-CORE", Info: "Parameter \"transactions\" is not a list of transactions."}
+	}
+	indexes := make([]int, 0, len(types))
+	for index := range types {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	now := float64(s.now().Unix())
+	created := object == nil
+	if created {
+		object = Object{"fields": map[string]interface{}{"dateCreated": now}}
+		if search == "maniphest.search" {
+			object.SetField("fields.status.value", "open")
+			object.SetField("fields.authorPHID", s.Viewer["phid"])
+		}
+		s.store(search, object)
+	}
+	if created {
+		s.store(transactionSearch, s.historyEntry(object, "create", now))
+	}
+	object.SetField("fields.dateModified", now)
+
+	edit := Edit{Method: method, ObjectPHID: object["phid"].(string)}
+	result := make([]map[string]string, 0, len(indexes))
+	for _, index := range indexes {
+		txn := Transaction{
+			PHID: fmt.Sprintf("PHID-XACT-%d-%d", len(s.edits)+1, index),
+			Type: types[index],
+		}
+		if len(values[index]) == 1 {
+			txn.Value = values[index][0]
+		} else {
+			txn.Value = values[index]
+		}
+		path, mapped := maniphestFields[txn.Type]
+		if !mapped || search != "maniphest.search" {
+			path = "fields." + txn.Type
+		}
+		entry := s.historyEntry(object, txn.Type, now)
+		entry["phid"] = txn.PHID
+		switch {
+		case txn.Type == "comment":
+			entry["comments"] = []interface{}{map[string]interface{}{
+				"id": len(s.edits) + 1, "phid": fmt.Sprintf("PHID-XCMT-%d-%d", len(s.edits)+1, index),
+				"version": 1, "authorPHID": s.Viewer["phid"], "dateCreated": now, "dateModified": now,
+				"removed": false, "content": map[string]interface{}{"raw": txn.Value},
+			}}
+		case strings.Contains(txn.Type, "."):
+			// Edge edits such as projects.add are only recorded
+			edge := strings.SplitN(txn.Type, ".", 2)
+			entry["type"] = edge[0]
+			operations := []interface{}{}
+			for _, phid := range values[index] {
+				operations = append(operations, map[string]interface{}{"operation": edge[1], "phid": phid})
+			}
+			entry.SetField("fields.operations", operations)
+		default:
+			entry.SetField("fields.old", object.Field(path))
+			entry.SetField("fields.new", txn.Value)
+			object.SetField(path, txn.Value)
+		}
+		s.store(transactionSearch, entry)
+		edit.Transactions = append(edit.Transactions, txn)
+		result = append(result, map[string]string{"phid": txn.PHID})
+	}
+	s.edits = append(s.edits, edit)
+
+	return map[string]interface{}{
+		"object":       map[string]interface{}{"id": object["id"], "phid": object["phid"]},
+		"transactions": result,
+	}, nil
+}
@@ -0,0 +1,279 @@
+package phabtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.showmax.cc/phabricator"
+	"go.showmax.cc/phabricator/transaction"
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func newClient(t *testing.T, server *Server, opts ...phabricator.Option) *phabricator.Phabricator {
+	t.Helper()
+	phab, err := phabricator.New(context.Background(), append([]phabricator.Option{
+		phabricator.WithAPI(server.API()),
+		phabricator.WithToken("api-test"),
+		phabricator.WithLogLevel("panic"),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return phab
+}
+
+func newTicket(name string, status phabTypes.TicketStatus) phabTypes.Ticket {
+	var ticket phabTypes.Ticket
+	ticket.Fields.Name = name
+	ticket.Fields.Status.Value = status
+	return ticket
+}
+
+func searchTickets(ctx context.Context, phab *phabricator.Phabricator, args phabTypes.TicketSearchArgs) ([]*phabTypes.Ticket, error) {
+	var tickets []*phabTypes.Ticket
+	for result := range phab.CallSearch(ctx, "maniphest.search", args, phabTypes.Ticket{}) {
+		if err, isErr := result.(error); isErr {
+			return nil, err
+		}
+		tickets = append(tickets, result.(*phabTypes.Ticket))
+	}
+	return tickets, nil
+}
+
+func TestSearchPagination(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.PageSize = 2
+	for _, status := range []phabTypes.TicketStatus{"open", "resolved", "open", "open", "open"} {
+		if err := server.Add("maniphest.search", newTicket("Task", status)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	phab := newClient(t, server)
+	var args phabTypes.TicketSearchArgs
+	args.Constraints.Statuses = []phabTypes.TicketStatus{phabTypes.TicketStatusOpen}
+	tickets, err := searchTickets(ctx, phab, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 4 {
+		t.Errorf("Expected 4 open tickets, got %d", len(tickets))
+	}
+	pages := 0
+	for _, call := range server.Calls() {
+		if call.Method == "maniphest.search" {
+			pages++
+		}
+	}
+	if pages != 2 {
+		t.Errorf("Expected two pages, got %d", pages)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Add("maniphest.search", newTicket("Existing", "open"))
+
+	ctx := context.Background()
+	phab := newClient(t, server)
+	err := phab.CallEdit(ctx, "maniphest.edit", &phabricator.EditArguments{
+		ObjectIdentifier: "T1",
+		Transactions: []phabricator.PhabTransaction{
+			phabricator.NewTransaction("status", "resolved"),
+			phabricator.NewTransaction("comment", "Done"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = phab.CallEdit(ctx, "maniphest.edit", &phabricator.EditArguments{
+		Transactions: []phabricator.PhabTransaction{phabricator.NewTransaction("title", "New task")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects := server.Objects("maniphest.search")
+	if len(objects) != 2 {
+		t.Fatalf("Expected a new task, got %v", objects)
+	}
+	if status := objects[0].Field("fields.status.value"); status != "resolved" {
+		t.Errorf("Status not edited: %v", status)
+	}
+	if name := objects[1].Field("fields.name"); name != "New task" || objects[1]["phid"] != "PHID-TASK-2" {
+		t.Errorf("Unexpected new task %v", objects[1])
+	}
+	if edits := server.Edits(); len(edits) != 2 || edits[0].Transactions[1].Value != "Done" {
+		t.Errorf("Unexpected edits %+v", edits)
+	}
+
+	err = phab.CallEdit(ctx, "maniphest.edit", &phabricator.EditArguments{
+		ObjectIdentifier: "T99",
+		Transactions:     []phabricator.PhabTransaction{phabricator.NewTransaction("title", "Missing")},
+	})
+	if err == nil {
+		t.Error("Edit of a missing task succeeded")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	if err := server.Add("maniphest.search", newTicket("Crash", phabTypes.TicketStatusOpen)); err != nil {
+		t.Fatal(err)
+	}
+	server.Handle("maniphest.status.search", func(url.Values) (interface{}, error) {
+		return map[string]interface{}{"data": []map[string]interface{}{
+			{"value": "open", "name": "Open"},
+			{"value": "resolved", "name": "Resolved", "closed": true},
+		}}, nil
+	})
+	server.Handle("maniphest.priority.search", func(url.Values) (interface{}, error) {
+		return map[string]interface{}{"data": []interface{}{}}, nil
+	})
+	// Validated against the parameters the server reports
+	phab := newClient(t, server, phabricator.WithArgumentValidation())
+
+	for _, transactions := range [][]phabricator.PhabTransaction{
+		{phabricator.NewTransaction("title", "Crash on start")},
+		{phabricator.NewTransaction("status", "resolved"), phabricator.NewTransaction("comment", "Fixed")},
+		{phabricator.NewTransaction("projects.add", []string{"PHID-PROJ-1"})},
+	} {
+		err := phab.CallEdit(ctx, "maniphest.edit", &phabricator.EditArguments{
+			ObjectIdentifier: "T1",
+			Transactions:     transactions,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := phab.Transactions(ctx, transaction.Query("T1").Args())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, change := range history {
+		types = append(types, string(change.Type))
+	}
+	if strings.Join(types, ",") != "title,status,comment,projects" {
+		t.Fatalf("Unexpected history %v", types)
+	}
+	if history[0].Fields.OldText() != "Crash" || history[0].Fields.NewText() != "Crash on start" || history[0].ObjectPHID != "PHID-TASK-1" {
+		t.Errorf("Unexpected title change %+v", history[0])
+	}
+	if history[2].Comment() != "Fixed" || history[3].Fields.Operations[0].Phid != "PHID-PROJ-1" {
+		t.Errorf("Unexpected comment or edge change %v, %+v", history[2], history[3].Fields)
+	}
+
+	history, err = phab.Transactions(ctx, transaction.Query("PHID-TASK-1").
+		Types(phabTypes.TransactionTypeStatus, phabTypes.TransactionTypeComment).
+		AuthoredBy("PHID-USER-viewer").
+		Args())
+	if err != nil || len(history) != 2 {
+		t.Errorf("Expected the status change and comment, got %v (%v)", history, err)
+	}
+	if history, err = phab.Transactions(ctx, transaction.Query("T1").AuthoredBy("PHID-USER-other").Args()); err != nil || len(history) != 0 {
+		t.Errorf("Author filter not applied: %v (%v)", history, err)
+	}
+	if _, err := phab.Transactions(ctx, transaction.Query("T99").Args()); err == nil {
+		t.Error("Expected an error for a missing object")
+	}
+	if _, err := phab.Transactions(ctx, transaction.Query("").Args()); err == nil {
+		t.Error("Expected an error without an object")
+	}
+}
+
+func TestFailures(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Token = "api-test"
+	server.Add("maniphest.search")
+
+	ctx := context.Background()
+	phab := newClient(t, server)
+	server.FailNext("maniphest.search", "ERR-CONDUIT-CORE", "Injected")
+	_, err := searchTickets(ctx, phab, phabTypes.TicketSearchArgs{})
+	var conduitErr *phabricator.ConduitError
+	if !errors.As(err, &conduitErr) || conduitErr.Info != "Injected" {
+		t.Errorf("Expected the injected error, got %v", err)
+	}
+	if _, err := searchTickets(ctx, phab, phabTypes.TicketSearchArgs{}); err != nil {
+		t.Errorf("Failure not limited to a single call: %s", err)
+	}
+
+	server.FailNextHTTP("*", 502)
+	if _, err := phab.WhoAmI(ctx); err == nil {
+		t.Error("HTTP failure not reported")
+	}
+
+	var args phabTypes.TicketSearchArgs
+	args.Constraints.Projects = []string{"PHID-PROJ-1"}
+	if _, err := searchTickets(ctx, phab, args); err == nil {
+		t.Error("Unknown constraint accepted")
+	}
+	server.AddConstraint("maniphest.search", "projects", func(Object, []string) bool { return true })
+	if _, err := searchTickets(ctx, phab, args); err != nil {
+		t.Error(err)
+	}
+
+	server.Token = "api-other"
+	if _, err := phab.WhoAmI(ctx); !errors.As(err, &conduitErr) || conduitErr.Code != "ERR-INVALID-AUTH" {
+		t.Errorf("Wrong token accepted: %v", err)
+	}
+}
+
+func TestCertificateSession(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Certificates = map[string]string{"alice": "certificate"}
+	server.Add("maniphest.search", newTicket("First", "open"))
+
+	connect := func(certificate string) *phabricator.Phabricator {
+		phab, err := phabricator.New(context.Background(),
+			phabricator.WithAPI(server.API()),
+			phabricator.WithTokenSource(phabricator.TokenSourceFunc(
+				func(ctx context.Context, api string) (*phabricator.Credentials, error) {
+					return &phabricator.Credentials{API: api, User: "alice", Certificate: certificate}, nil
+				})),
+			phabricator.WithLogLevel("panic"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return phab
+	}
+	ctx := context.Background()
+	tickets, err := searchTickets(ctx, connect("certificate"), phabTypes.TicketSearchArgs{})
+	if err != nil || len(tickets) != 1 {
+		t.Fatalf("Search with a session failed: %v, %v", tickets, err)
+	}
+	if _, err := searchTickets(ctx, connect("forged"), phabTypes.TicketSearchArgs{}); err == nil {
+		t.Error("Forged certificate accepted")
+	}
+
+	// A session nested in __conduit__ isn't read by Phabricator
+	resp, err := http.PostForm(server.API()+"maniphest.search", url.Values{
+		"__conduit__[sessionKey]":   {"session-1"},
+		"__conduit__[connectionID]": {"1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var response struct {
+		ErrorCode string `json:"error_code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil || response.ErrorCode != "ERR-INVALID-SESSION" {
+		t.Errorf("Search without a valid session answered with %q (%v)", response.ErrorCode, err)
+	}
+}
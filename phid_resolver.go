@@ -0,0 +1,162 @@
+package phabricator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"time"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// PHID types of the most common objects, as in PHID-TASK-...
+const (
+	PHIDTypeUser       = "USER"
+	PHIDTypeTask       = "TASK"
+	PHIDTypeProject    = "PROJ"
+	PHIDTypeRevision   = "DREV"
+	PHIDTypeDiff       = "DIFF"
+	PHIDTypeRepository = "REPO"
+	PHIDTypeCommit     = "CMIT"
+)
+
+const (
+	// PHIDs resolved by a single phid.query call
+	phidQueryBatchSize = 100
+)
+
+// phidType returns the type part of PHID, e.g. TASK for PHID-TASK-abc,
+// or an empty string if PHID isn't a PHID
+func phidType(phid string) string {
+	return phabTypes.PHID(phid).Type()
+}
+
+// PHIDResolver turns PHIDs into object handles with names and URIs
+// using phid.query. Calls are batched and results cached, so rendering
+// a report costs a handful of requests instead of one per PHID.
+type PHIDResolver struct {
+	phab  *Phabricator
+	cache *ttlCache
+}
+
+// NewPHIDResolver creates a resolver using PHAB. Results are cached
+// for TTL, a zero TTL caches them for the resolver's lifetime.
+func NewPHIDResolver(phab *Phabricator, ttl time.Duration) *PHIDResolver {
+	return &PHIDResolver{phab: phab, cache: newTTLCache(ttl)}
+}
+
+// Resolve returns handles of PHIDS. Objects that don't exist or
+// aren't visible to the viewer are missing from the result.
+// Strings that aren't PHIDs are ignored.
+func (r *PHIDResolver) Resolve(ctx context.Context, phids ...string) (map[string]*ObjectHandle, error) {
+	result := make(map[string]*ObjectHandle, len(phids))
+	pending := make(map[string]bool)
+	var missing []string
+	for _, phid := range phids {
+		if phidType(phid) == "" {
+			logger.WithField("phid", phid).Debug("Not a PHID - skipping")
+			continue
+		}
+		if cached, hit := r.cache.get(phid); hit {
+			result[phid] = cached.(*ObjectHandle)
+			continue
+		}
+		if !pending[phid] {
+			pending[phid] = true
+			missing = append(missing, phid)
+		}
+	}
+
+	for start := 0; start < len(missing); start += phidQueryBatchSize {
+		end := start + phidQueryBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		handles, err := r.phab.queryPHIDs(ctx, missing[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for phid, handle := range handles {
+			r.cache.set(phid, handle)
+			result[phid] = handle
+		}
+	}
+	return result, nil
+}
+
+// ResolveType is like Resolve, but only considers PHIDs of type TYP,
+// e.g. PHIDTypeUser
+func (r *PHIDResolver) ResolveType(ctx context.Context, typ string, phids ...string) (map[string]*ObjectHandle, error) {
+	filtered := make([]string, 0, len(phids))
+	for _, phid := range phids {
+		if phidType(phid) == typ {
+			filtered = append(filtered, phid)
+		}
+	}
+	return r.Resolve(ctx, filtered...)
+}
+
+// Hydrate collects every PHID found anywhere in VALUES - search results
+// such as *types.Ticket, slices of them, maps keyed by PHIDs etc. - and
+// resolves them all at once
+func (r *PHIDResolver) Hydrate(ctx context.Context, values ...interface{}) (map[string]*ObjectHandle, error) {
+	found := make(map[string]bool)
+	for _, value := range values {
+		collectPHIDs(reflect.ValueOf(value), found)
+	}
+	phids := make([]string, 0, len(found))
+	for phid := range found {
+		phids = append(phids, phid)
+	}
+	logger.WithField("phids", len(phids)).Debug("Hydrating PHIDs")
+	return r.Resolve(ctx, phids...)
+}
+
+// collectPHIDs walks V and adds all strings that look like PHIDs to FOUND
+func collectPHIDs(v reflect.Value, found map[string]bool) {
+	switch v.Kind() {
+	case reflect.String:
+		if phidType(v.String()) != "" {
+			found[v.String()] = true
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectPHIDs(v.Elem(), found)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			collectPHIDs(v.Field(i), found)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectPHIDs(v.Index(i), found)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectPHIDs(iter.Key(), found)
+			collectPHIDs(iter.Value(), found)
+		}
+	}
+}
+
+// queryPHIDs calls phid.query with PHIDS
+func (p *Phabricator) queryPHIDs(ctx context.Context, phids []string) (map[string]*ObjectHandle, error) {
+	params := url.Values{}
+	for i, phid := range phids {
+		params.Set(fmt.Sprintf("phids[%d]", i), phid)
+	}
+	handles := make(map[string]*ObjectHandle)
+	if err := p.call(ctx, "phid.query", params.Encode(), &handles); err != nil {
+		return nil, err
+	}
+	logger.WithFields(log.Fields{
+		"requested": len(phids),
+		"found":     len(handles),
+	}).Debug("Queried PHIDs")
+	return handles, nil
+}
@@ -0,0 +1,113 @@
+package phabricator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// phidQueryServer answers phid.query with a handle for every PHID requested
+func phidQueryServer(requests *int32, batchSizes *[]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		r.ParseForm()
+		handles := make(map[string]ObjectHandle)
+		for key, values := range r.Form {
+			if strings.HasPrefix(key, "phids[") {
+				phid := values[0]
+				handles[phid] = ObjectHandle{PHID: phid, Type: phidType(phid), Name: "name of " + phid}
+			}
+		}
+		*batchSizes = append(*batchSizes, len(handles))
+		result, _ := json.Marshal(handles)
+		fmt.Fprintf(w, `{"result": %s}`, result)
+	}))
+}
+
+func TestPHIDResolverBatches(t *testing.T) {
+	var requests int32
+	var batchSizes []int
+	server := phidQueryServer(&requests, &batchSizes)
+	defer server.Close()
+
+	ctx := context.Background()
+	phab, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-token"), WithLogLevel("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewPHIDResolver(phab, time.Minute)
+	phids := []string{"not-a-phid"}
+	for i := 0; i < 150; i++ {
+		phids = append(phids, fmt.Sprintf("PHID-USER-%d", i))
+	}
+	handles, err := resolver.Resolve(ctx, phids...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handles) != 150 {
+		t.Errorf("Expected 150 handles, got %d", len(handles))
+	}
+	if len(batchSizes) != 2 || batchSizes[0]+batchSizes[1] != 150 {
+		t.Errorf("Unexpected batches %v", batchSizes)
+	}
+
+	if _, err := resolver.Resolve(ctx, "PHID-USER-1", "PHID-USER-149"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Cached PHIDs queried again, %d requests", n)
+	}
+}
+
+func TestPHIDResolverHydrate(t *testing.T) {
+	var requests int32
+	var batchSizes []int
+	server := phidQueryServer(&requests, &batchSizes)
+	defer server.Close()
+
+	ctx := context.Background()
+	phab, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-token"), WithLogLevel("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ticket phabTypes.Ticket
+	ticket.Phid = "PHID-TASK-1"
+	ticket.Fields.OwnerPHID = "PHID-USER-owner"
+	ticket.Attachments.Subscribers.SubscriberPHIDs = []string{"PHID-USER-owner", "PHID-USER-watcher"}
+	ticket.Attachments.Columns.Boards = map[string]phabTypes.TicketAttachmentBoard{
+		"PHID-PROJ-board": {},
+	}
+	var revision phabTypes.Revision
+	revision.Attachments.Reviewers.Reviewers = []phabTypes.RevisionReviewer{{ReviewerPHID: "PHID-USER-reviewer"}}
+
+	resolver := NewPHIDResolver(phab, 0)
+	handles, err := resolver.Hydrate(ctx, &ticket, []*phabTypes.Revision{&revision})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, phid := range []string{"PHID-TASK-1", "PHID-USER-owner", "PHID-USER-watcher", "PHID-PROJ-board", "PHID-USER-reviewer"} {
+		if _, found := handles[phid]; !found {
+			t.Errorf("%s not hydrated", phid)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Hydrate sent %d requests, expected 1", n)
+	}
+
+	users, err := resolver.ResolveType(ctx, PHIDTypeUser, "PHID-TASK-1", "PHID-USER-owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users["PHID-USER-owner"] == nil {
+		t.Errorf("Unexpected users %v", users)
+	}
+}
@@ -0,0 +1,135 @@
+package phabricator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// PHIDTypeInfo tells how to fetch objects of a single PHID type
+type PHIDTypeInfo struct {
+	// 4-letter PHID type, e.g. TASK
+	Type string
+	// Search endpoint returning the objects, e.g. maniphest.search
+	Endpoint string
+	// Go type results are decoded to, e.g. types.Ticket
+	Result reflect.Type
+}
+
+var phidTypes = struct {
+	sync.RWMutex
+	types map[string]PHIDTypeInfo
+}{types: make(map[string]PHIDTypeInfo)}
+
+// RegisterPHIDType makes objects of PHID type TYP fetchable from ENDPOINT,
+// decoded into values of the same type as RESULT.
+// Registering an already known type replaces it.
+func RegisterPHIDType(typ, endpoint string, result interface{}) {
+	phidTypes.Lock()
+	defer phidTypes.Unlock()
+	phidTypes.types[typ] = PHIDTypeInfo{
+		Type:     typ,
+		Endpoint: endpoint,
+		Result:   reflect.TypeOf(result),
+	}
+}
+
+// LookupPHIDType returns what's registered for PHID type TYP
+func LookupPHIDType(typ string) (PHIDTypeInfo, bool) {
+	phidTypes.RLock()
+	defer phidTypes.RUnlock()
+	info, found := phidTypes.types[typ]
+	return info, found
+}
+
+func init() {
+	RegisterPHIDType(PHIDTypeUser, "user.search", phabTypes.User{})
+	RegisterPHIDType(PHIDTypeTask, "maniphest.search", phabTypes.Ticket{})
+	RegisterPHIDType(PHIDTypeProject, "project.search", phabTypes.Project{})
+	RegisterPHIDType(PHIDTypeRevision, "differential.revision.search", phabTypes.Revision{})
+	RegisterPHIDType(PHIDTypeDiff, "differential.diff.search", phabTypes.Diff{})
+	RegisterPHIDType(PHIDTypeRepository, "diffusion.repository.search", phabTypes.Repository{})
+}
+
+// phidSearchArgs are the arguments shared by all *.search endpoints
+// to constrain the results to given PHIDs
+type phidSearchArgs struct {
+	Constraints struct {
+		Phids []phabTypes.PHID `url:"phids,brackets"`
+	} `url:"constraints"`
+}
+
+// Fetch returns the object identified by PHID, e.g. *types.Ticket for
+// a PHID-TASK-... The PHID type must be registered, see RegisterPHIDType.
+func (p *Phabricator) Fetch(ctx context.Context, phid phabTypes.PHID) (interface{}, error) {
+	objects, err := p.FetchAll(ctx, phid)
+	if err != nil {
+		return nil, err
+	}
+	object, found := objects[phid]
+	if !found {
+		return nil, fmt.Errorf("Object %s not found", phid)
+	}
+	return object, nil
+}
+
+// FetchAll returns the objects identified by PHIDS, using one search
+// call per PHID type. Objects that don't exist or aren't visible are
+// missing from the result.
+func (p *Phabricator) FetchAll(ctx context.Context, phids ...phabTypes.PHID) (map[phabTypes.PHID]interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	byType := make(map[string][]phabTypes.PHID)
+	for _, phid := range phids {
+		if !phid.Valid() {
+			return nil, fmt.Errorf("Invalid PHID %q", phid)
+		}
+		byType[phid.Type()] = append(byType[phid.Type()], phid)
+	}
+
+	result := make(map[phabTypes.PHID]interface{}, len(phids))
+	for typ, typePHIDs := range byType {
+		info, known := LookupPHIDType(typ)
+		if !known {
+			return nil, fmt.Errorf("Don't know how to fetch objects of PHID type %s", typ)
+		}
+		for start := 0; start < len(typePHIDs); start += maxBufferedResponses {
+			end := start + maxBufferedResponses
+			if end > len(typePHIDs) {
+				end = len(typePHIDs)
+			}
+			var args phidSearchArgs
+			args.Constraints.Phids = typePHIDs[start:end]
+			results := p.CallSearch(ctx, info.Endpoint, args, reflect.Zero(info.Result).Interface())
+			if results == nil {
+				return nil, fmt.Errorf("Endpoint %s is not available", info.Endpoint)
+			}
+			for r := range results {
+				if err, isErr := r.(error); isErr {
+					cancel()
+					drain(results)
+					return nil, err
+				}
+				phid := reflect.Indirect(reflect.ValueOf(r)).FieldByName("Phid")
+				if !phid.IsValid() || phid.Kind() != reflect.String {
+					cancel()
+					drain(results)
+					return nil, fmt.Errorf("%s has no Phid field", info.Result)
+				}
+				result[phabTypes.PHID(phid.String())] = r
+			}
+		}
+		logger.WithFields(log.Fields{
+			"type":     typ,
+			"endpoint": info.Endpoint,
+			"count":    len(typePHIDs),
+		}).Debug("Fetched objects by PHID")
+	}
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package phabricator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func TestFetchByPHID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/api/conduit.query":
+			fmt.Fprint(w, `{"result": {"maniphest.search": {"params":[]}, "user.search": {"params":[]}}}`)
+		case "/api/maniphest.search", "/api/user.search":
+			var data []map[string]interface{}
+			for key, values := range r.Form {
+				if strings.HasPrefix(key, "constraints[phids]") && !strings.HasSuffix(values[0], "-missing") {
+					data = append(data, map[string]interface{}{"id": 1, "phid": values[0]})
+				}
+			}
+			result, _ := json.Marshal(data)
+			fmt.Fprintf(w, `{"result": {"data": %s, "cursor": {"after": null}}}`, result)
+		default:
+			fmt.Fprint(w, `{"error_code": "ERR-CONDUIT-CORE", "error_info": "Unknown method"}`)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	phab, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-token"), WithLogLevel("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object, err := phab.Fetch(ctx, "PHID-TASK-abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket, ok := object.(*phabTypes.Ticket); !ok || ticket.Phid != "PHID-TASK-abc" {
+		t.Errorf("Expected a ticket, got %#v", object)
+	}
+
+	objects, err := phab.FetchAll(ctx, "PHID-TASK-1", "PHID-USER-1", "PHID-USER-missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects["PHID-USER-1"].(*phabTypes.User); !ok || len(objects) != 2 {
+		t.Errorf("Unexpected objects %v", objects)
+	}
+
+	if _, err := phab.Fetch(ctx, "PHID-TASK-missing"); err == nil {
+		t.Error("Missing object fetched")
+	}
+	if _, err := phab.Fetch(ctx, "PHID-XXXX-1"); err == nil {
+		t.Error("Unregistered PHID type fetched")
+	}
+	if _, err := phab.Fetch(ctx, "T1"); err == nil {
+		t.Error("Invalid PHID fetched")
+	}
+}
@@ -0,0 +1,103 @@
+package phabricator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+)
+
+// Roles reported by user.whoami
+const (
+	RoleAdmin       = "admin"
+	RoleDisabled    = "disabled"
+	RoleBot         = "bot"
+	RoleMailingList = "list"
+	RoleVerified    = "verified"
+	RoleApproved    = "approved"
+	RoleActivated   = "activated"
+)
+
+// Conduit error codes meaning the credentials themselves were rejected
+var authErrorCodes = map[string]bool{
+	"ERR-INVALID-AUTH":    true,
+	"ERR-INVALID-SESSION": true,
+	"ERR-INVALID-TOKEN":   true,
+}
+
+// HasRole reports whether the user has ROLE
+func (w *WhoAmI) HasRole(role string) bool {
+	for _, r := range w.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBot reports whether the user is a bot account
+func (w *WhoAmI) IsBot() bool {
+	return w.HasRole(RoleBot)
+}
+
+// IsDisabled reports whether the user account is disabled
+func (w *WhoAmI) IsDisabled() bool {
+	return w.HasRole(RoleDisabled)
+}
+
+// IsApproved reports whether an administrator approved the account
+func (w *WhoAmI) IsApproved() bool {
+	return w.HasRole(RoleApproved)
+}
+
+// AuthError is returned by the preflight check when the credentials
+// can't be used. Viewer is nil if Phabricator rejected the credentials
+// outright, Err holds the underlying error if there is one.
+type AuthError struct {
+	Reason string
+	Viewer *WhoAmI
+	Err    error
+}
+
+func (e *AuthError) Error() string {
+	if e.Viewer != nil {
+		return fmt.Sprintf("Authentication failed for %s: %s", e.Viewer.Username, e.Reason)
+	}
+	return fmt.Sprintf("Authentication failed: %s", e.Reason)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// preflight verifies the credentials work and belong to a usable account,
+// so that jobs fail at start instead of halfway through
+func (p *Phabricator) preflight(ctx context.Context) error {
+	viewer, err := p.Viewer(ctx)
+	if err != nil {
+		var cerr *ConduitError
+		if errors.As(err, &cerr) && authErrorCodes[cerr.Code] {
+			return &AuthError{Reason: cerr.Info, Err: err}
+		}
+		return err
+	}
+	logger := logger.WithFields(log.Fields{
+		"viewer":   viewer.Username,
+		"phid":     viewer.PHID,
+		"bot":      viewer.IsBot(),
+		"disabled": viewer.IsDisabled(),
+		"approved": viewer.IsApproved(),
+	})
+	if viewer.IsDisabled() {
+		logger.Error("Account is disabled")
+		return &AuthError{Reason: "account is disabled", Viewer: viewer}
+	}
+	if !viewer.IsApproved() {
+		logger.Error("Account is not approved")
+		return &AuthError{Reason: "account is not approved", Viewer: viewer}
+	}
+	logger.Info("Preflight check passed")
+	return nil
+}
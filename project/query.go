@@ -0,0 +1,130 @@
+// Package project builds arguments for project.search
+//
+//	args := project.Query().Slugs("backend").WithMembers().Args()
+//	results := phab.CallSearch(ctx, project.Endpoint, args, phabTypes.Project{})
+package project
+
+import (
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// Endpoint the built arguments are meant for
+const Endpoint = "project.search"
+
+// Builder builds ProjectSearchArgs. Every method modifies the builder
+// and returns it, so calls can be chained.
+type Builder struct {
+	args phabTypes.ProjectSearchArgs
+}
+
+// Query starts a new, unconstrained project query
+func Query() *Builder {
+	return &Builder{}
+}
+
+// Args returns the built arguments
+func (b *Builder) Args() phabTypes.ProjectSearchArgs {
+	return b.args
+}
+
+// QueryKey starts from a saved or builtin query, e.g. active or joined
+func (b *Builder) QueryKey(key string) *Builder {
+	b.args.QueryKey = key
+	return b
+}
+
+// IDs limits the results to projects with IDS
+func (b *Builder) IDs(ids ...int) *Builder {
+	b.args.Constraints.Ids = append(b.args.Constraints.Ids, ids...)
+	return b
+}
+
+// PHIDs limits the results to projects with PHIDS
+func (b *Builder) PHIDs(phids ...string) *Builder {
+	b.args.Constraints.Phids = append(b.args.Constraints.Phids, phids...)
+	return b
+}
+
+// Slugs limits the results to projects with any of SLUGS, e.g. backend for #backend
+func (b *Builder) Slugs(slugs ...string) *Builder {
+	b.args.Constraints.Slugs = append(b.args.Constraints.Slugs, slugs...)
+	return b
+}
+
+// WithMember limits the results to projects all of the users with PHIDS are members of
+func (b *Builder) WithMember(phids ...string) *Builder {
+	b.args.Constraints.Members = append(b.args.Constraints.Members, phids...)
+	return b
+}
+
+// WatchedBy limits the results to projects all of the users with PHIDS watch
+func (b *Builder) WatchedBy(phids ...string) *Builder {
+	b.args.Constraints.Watchers = append(b.args.Constraints.Watchers, phids...)
+	return b
+}
+
+// Milestones limits the results to milestones
+func (b *Builder) Milestones() *Builder {
+	b.args.Constraints.IsMilestone = true
+	return b
+}
+
+// ChildrenOf limits the results to direct subprojects of the projects with PHIDS
+func (b *Builder) ChildrenOf(phids ...string) *Builder {
+	b.args.Constraints.Parents = append(b.args.Constraints.Parents, phids...)
+	return b
+}
+
+// DescendantsOf limits the results to subprojects at any depth of the projects with PHIDS
+func (b *Builder) DescendantsOf(phids ...string) *Builder {
+	b.args.Constraints.Ancestors = append(b.args.Constraints.Ancestors, phids...)
+	return b
+}
+
+// Icons limits the results to projects with any of ICONS, e.g. group
+func (b *Builder) Icons(icons ...string) *Builder {
+	b.args.Constraints.Icons = append(b.args.Constraints.Icons, icons...)
+	return b
+}
+
+// Colors limits the results to projects with any of COLORS, e.g. red
+func (b *Builder) Colors(colors ...string) *Builder {
+	b.args.Constraints.Colors = append(b.args.Constraints.Colors, colors...)
+	return b
+}
+
+// InSpaces limits the results to projects in any of the spaces with PHIDS
+func (b *Builder) InSpaces(phids ...string) *Builder {
+	b.args.Constraints.Spaces = append(b.args.Constraints.Spaces, phids...)
+	return b
+}
+
+// Matching limits the results to projects matching the full-text query TEXT
+func (b *Builder) Matching(text string) *Builder {
+	b.args.Constraints.Query = text
+	return b
+}
+
+// Order sets the result order, e.g. name
+func (b *Builder) Order(order string) *Builder {
+	b.args.Order = order
+	return b
+}
+
+// WithMembers attaches the members of the projects
+func (b *Builder) WithMembers() *Builder {
+	b.args.Attachments.Members = true
+	return b
+}
+
+// WithWatchers attaches the watchers of the projects
+func (b *Builder) WithWatchers() *Builder {
+	b.args.Attachments.Watchers = true
+	return b
+}
+
+// WithAncestors attaches the parent projects of the projects
+func (b *Builder) WithAncestors() *Builder {
+	b.args.Attachments.Ancestors = true
+	return b
+}
@@ -0,0 +1,40 @@
+package project
+
+import (
+	"testing"
+
+	query "github.com/google/go-querystring/query"
+)
+
+func TestQuery(t *testing.T) {
+	args := Query().
+		Slugs("backend", "api").
+		WithMember("PHID-USER-me").
+		Milestones().
+		Matching("platform").
+		Order("name").
+		WithMembers().
+		Args()
+
+	values, err := query.Values(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"constraints[members][]":   "PHID-USER-me",
+		"constraints[isMilestone]": "true",
+		"constraints[query]":       "platform",
+		"order":                    "name",
+		"attachments[members]":     "true",
+	} {
+		if value := values.Get(key); value != expected {
+			t.Errorf("%s: expected %q, got %q", key, expected, value)
+		}
+	}
+	if slugs := values["constraints[slugs][]"]; len(slugs) != 2 || slugs[1] != "api" {
+		t.Errorf("Expected slugs backend and api, got %v", slugs)
+	}
+	if _, set := values["attachments[watchers]"]; set {
+		t.Error("Watchers attached without being asked for")
+	}
+}
@@ -0,0 +1,407 @@
+package querylang
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.showmax.cc/phabricator/differential"
+	"go.showmax.cc/phabricator/maniphest"
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// Relative times such as 7d: hours, days, weeks, months or years ago
+var relativeTime = regexp.MustCompile(`^([0-9]+)([hdwmy])$`)
+
+// Repository monograms by ID, callsigns such as RAPI are prefixed with r
+var repositoryID = regexp.MustCompile(`^R[0-9]+$`)
+
+// Compiler turns queries into search arguments
+type Compiler struct {
+	Resolver Resolver
+	// Now is the time relative dates are counted from, time.Now if nil
+	Now func() time.Time
+}
+
+// NewCompiler creates a compiler resolving names with RESOLVER
+func NewCompiler(resolver Resolver) *Compiler {
+	return &Compiler{Resolver: resolver}
+}
+
+// compilation holds the state of compiling a single query
+type compilation struct {
+	*Compiler
+	ctx   context.Context
+	query string
+	words []string
+}
+
+func (c *compilation) errorf(pos int, format string, args ...interface{}) error {
+	return &Error{Query: c.query, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (c *Compiler) start(ctx context.Context, q string) (*compilation, []Term, error) {
+	terms, err := Parse(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &compilation{Compiler: c, ctx: ctx, query: q}, terms, nil
+}
+
+// Tickets compiles Q into maniphest.search arguments. Known keys are
+// status, priority, owner (or assigned), author, subscriber, project
+// (or tag), created, modified, closed, id and order.
+func (c *Compiler) Tickets(ctx context.Context, q string) (phabTypes.TicketSearchArgs, error) {
+	comp, terms, err := c.start(ctx, q)
+	if err != nil {
+		return phabTypes.TicketSearchArgs{}, err
+	}
+	b := maniphest.Query()
+	for _, term := range terms {
+		var phids []string
+		var err error
+		switch term.Key {
+		case "":
+			comp.word(term)
+		case "status":
+			var statuses []phabTypes.TicketStatus
+			if statuses, err = comp.ticketStatuses(term); err == nil {
+				b.Status(statuses...)
+			}
+		case "priority":
+			var priorities []phabTypes.TicketPriority
+			if priorities, err = comp.ticketPriorities(term); err == nil {
+				b.Priority(priorities...)
+			}
+		case "owner", "assigned":
+			if phids, err = comp.users(term, true); err == nil {
+				b.AssignedTo(phids...)
+			}
+		case "author":
+			if phids, err = comp.users(term, false); err == nil {
+				b.AuthoredBy(phids...)
+			}
+		case "subscriber":
+			if phids, err = comp.users(term, false); err == nil {
+				b.SubscribedBy(phids...)
+			}
+		case "project", "tag":
+			if phids, err = comp.projects(term); err == nil {
+				b.InProjects(phids...)
+			}
+		case "created", "modified", "closed":
+			var from, to time.Time
+			if from, to, err = comp.timeRange(term); err == nil {
+				after := map[string]func(time.Time) *maniphest.Builder{
+					"created": b.CreatedAfter, "modified": b.ModifiedAfter, "closed": b.ClosedAfter,
+				}
+				before := map[string]func(time.Time) *maniphest.Builder{
+					"created": b.CreatedBefore, "modified": b.ModifiedBefore, "closed": b.ClosedBefore,
+				}
+				if !from.IsZero() {
+					after[term.Key](from)
+				}
+				if !to.IsZero() {
+					before[term.Key](to)
+				}
+			}
+		case "id":
+			var ids []int
+			if ids, err = comp.ids(term, "T"); err == nil {
+				b.IDs(ids...)
+			}
+		case "order":
+			var order string
+			if order, err = comp.single(term); err == nil {
+				b.Order(order)
+			}
+		default:
+			err = comp.errorf(term.Pos, "Unknown key %q for tasks", term.Key)
+		}
+		if err != nil {
+			return phabTypes.TicketSearchArgs{}, err
+		}
+	}
+	if len(comp.words) > 0 {
+		b.Matching(strings.Join(comp.words, " "))
+	}
+	return b.Args(), nil
+}
+
+// Revisions compiles Q into differential.revision.search arguments.
+// Known keys are status, author, reviewer, responsible, subscriber,
+// project (or tag), repository (or repo), created, id and order.
+func (c *Compiler) Revisions(ctx context.Context, q string) (phabTypes.RevisionSearchArgs, error) {
+	comp, terms, err := c.start(ctx, q)
+	if err != nil {
+		return phabTypes.RevisionSearchArgs{}, err
+	}
+	b := differential.Query()
+	for _, term := range terms {
+		var phids []string
+		var err error
+		switch term.Key {
+		case "":
+			comp.word(term)
+		case "status":
+			for _, value := range term.Values {
+				status := phabTypes.RevisionStatus(strings.ToLower(value.Text))
+				if !status.Valid() {
+					return phabTypes.RevisionSearchArgs{}, comp.errorf(value.Pos, "Unknown revision status %q", value.Text)
+				}
+				b.Status(status)
+			}
+		case "author":
+			if phids, err = comp.users(term, false); err == nil {
+				b.AuthoredBy(phids...)
+			}
+		case "reviewer":
+			if phids, err = comp.users(term, false); err == nil {
+				b.ReviewedBy(phids...)
+			}
+		case "responsible":
+			if phids, err = comp.users(term, false); err == nil {
+				b.RespondedBy(phids...)
+			}
+		case "subscriber":
+			if phids, err = comp.users(term, false); err == nil {
+				b.SubscribedBy(phids...)
+			}
+		case "project", "tag":
+			if phids, err = comp.projects(term); err == nil {
+				b.InProjects(phids...)
+			}
+		case "repository", "repo":
+			if phids, err = comp.repositories(term); err == nil {
+				b.InRepositories(phids...)
+			}
+		case "created":
+			var from, to time.Time
+			if from, to, err = comp.timeRange(term); err == nil {
+				if !from.IsZero() {
+					b.CreatedAfter(from)
+				}
+				if !to.IsZero() {
+					b.CreatedBefore(to)
+				}
+			}
+		case "id":
+			var ids []int
+			if ids, err = comp.ids(term, "D"); err == nil {
+				b.IDs(ids...)
+			}
+		case "order":
+			var order string
+			if order, err = comp.single(term); err == nil {
+				b.Order(order)
+			}
+		default:
+			err = comp.errorf(term.Pos, "Unknown key %q for revisions", term.Key)
+		}
+		if err != nil {
+			return phabTypes.RevisionSearchArgs{}, err
+		}
+	}
+	if len(comp.words) > 0 {
+		b.Matching(strings.Join(comp.words, " "))
+	}
+	return b.Args(), nil
+}
+
+// word adds a full-text term, keeping phrases quoted
+func (c *compilation) word(term Term) {
+	value := term.Values[0]
+	if value.Quoted {
+		c.words = append(c.words, `"`+value.Text+`"`)
+		return
+	}
+	c.words = append(c.words, value.Text)
+}
+
+func (c *compilation) single(term Term) (string, error) {
+	if len(term.Values) != 1 {
+		return "", c.errorf(term.Values[1].Pos, "%q takes a single value", term.Key)
+	}
+	return term.Values[0].Text, nil
+}
+
+func (c *compilation) ticketStatuses(term Term) ([]phabTypes.TicketStatus, error) {
+	enums, err := c.Resolver.TicketEnums(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]phabTypes.TicketStatus, 0, len(term.Values))
+	for _, value := range term.Values {
+		status := phabTypes.TicketStatus(strings.ToLower(value.Text))
+		if _, known := enums.Status(status); !known && status != phabTypes.TicketStatusAnyOpen && status != phabTypes.TicketStatusAnyClosed {
+			return nil, c.errorf(value.Pos, "Unknown task status %q", value.Text)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (c *compilation) ticketPriorities(term Term) ([]phabTypes.TicketPriority, error) {
+	enums, err := c.Resolver.TicketEnums(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+	priorities := make([]phabTypes.TicketPriority, 0, len(term.Values))
+	for _, value := range term.Values {
+		if info, known := enums.PriorityByKeyword(value.Text); known {
+			priorities = append(priorities, info.Value)
+			continue
+		}
+		number, err := strconv.Atoi(value.Text)
+		if info, known := enums.Priority(phabTypes.TicketPriority(number)); err == nil && known {
+			priorities = append(priorities, info.Value)
+			continue
+		}
+		return nil, c.errorf(value.Pos, "Unknown task priority %q", value.Text)
+	}
+	return priorities, nil
+}
+
+// users resolves usernames with or without @, @me and PHIDs.
+// NOBODY allows none for unassigned tasks.
+func (c *compilation) users(term Term, nobody bool) ([]string, error) {
+	names := make([]string, len(term.Values))
+	for i, value := range term.Values {
+		name := strings.TrimPrefix(value.Text, "@")
+		switch {
+		case strings.EqualFold(name, "me"):
+			phid, err := c.Resolver.ViewerPHID(c.ctx)
+			if err != nil {
+				return nil, err
+			}
+			names[i] = phid
+		case nobody && strings.EqualFold(name, "none"):
+			names[i] = "null()"
+		case strings.HasPrefix(name, "PHID-"):
+			names[i] = name
+		default:
+			names[i] = "@" + name
+		}
+	}
+	return c.resolve(term, names, "user")
+}
+
+// projects resolves project slugs with or without # and PHIDs
+func (c *compilation) projects(term Term) ([]string, error) {
+	names := make([]string, len(term.Values))
+	for i, value := range term.Values {
+		name := value.Text
+		if !strings.HasPrefix(name, "PHID-") && !strings.HasPrefix(name, "#") {
+			name = "#" + name
+		}
+		names[i] = strings.ToLower(name)
+	}
+	return c.resolve(term, names, "project")
+}
+
+// repositories resolves callsigns with or without r, R123 and PHIDs
+func (c *compilation) repositories(term Term) ([]string, error) {
+	names := make([]string, len(term.Values))
+	for i, value := range term.Values {
+		name := value.Text
+		if !strings.HasPrefix(name, "PHID-") && !strings.HasPrefix(name, "r") && !repositoryID.MatchString(name) {
+			name = "r" + name
+		}
+		names[i] = name
+	}
+	return c.resolve(term, names, "repository")
+}
+
+// resolve turns the monograms in NAMES into PHIDs in a single call,
+// leaving PHIDs and functions such as null() as they are
+func (c *compilation) resolve(term Term, names []string, what string) ([]string, error) {
+	var lookup []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, "PHID-") && !strings.HasSuffix(name, "()") {
+			lookup = append(lookup, name)
+		}
+	}
+	var resolved map[string]string
+	if len(lookup) > 0 {
+		var err error
+		if resolved, err = c.Resolver.PHIDs(c.ctx, lookup...); err != nil {
+			return nil, c.errorf(term.Pos, "Can't resolve %s: %s", strings.Join(lookup, ", "), err)
+		}
+	}
+	phids := make([]string, len(names))
+	for i, name := range names {
+		if strings.HasPrefix(name, "PHID-") || strings.HasSuffix(name, "()") {
+			phids[i] = name
+			continue
+		}
+		phid, found := resolved[name]
+		if !found {
+			return nil, c.errorf(term.Values[i].Pos, "Unknown %s %q", what, term.Values[i].Text)
+		}
+		phids[i] = phid
+	}
+	return phids, nil
+}
+
+// ids parses plain IDs and monograms with PREFIX, e.g. T123
+func (c *compilation) ids(term Term, prefix string) ([]int, error) {
+	ids := make([]int, 0, len(term.Values))
+	for _, value := range term.Values {
+		id, err := strconv.Atoi(strings.TrimPrefix(value.Text, prefix))
+		if err != nil || id <= 0 {
+			return nil, c.errorf(value.Pos, "Invalid ID %q", value.Text)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// timeRange turns a date term into a range with either end possibly
+// open. >7d is within the last seven days, <7d more than seven days ago.
+// A date without a comparison means that day, other times since then.
+func (c *compilation) timeRange(term Term) (from, to time.Time, err error) {
+	value := term.Values[0]
+	if len(term.Values) > 1 {
+		return from, to, c.errorf(term.Values[1].Pos, "%q takes a single value", term.Key)
+	}
+	now := time.Now()
+	if c.Now != nil {
+		now = c.Now()
+	}
+
+	var t time.Time
+	day := false
+	if match := relativeTime.FindStringSubmatch(value.Text); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "h":
+			t = now.Add(-time.Duration(n) * time.Hour)
+		case "d":
+			t = now.AddDate(0, 0, -n)
+		case "w":
+			t = now.AddDate(0, 0, -7*n)
+		case "m":
+			t = now.AddDate(0, -n, 0)
+		case "y":
+			t = now.AddDate(-n, 0, 0)
+		}
+	} else if t, err = time.ParseInLocation("2006-01-02", value.Text, now.Location()); err == nil {
+		day = true
+	} else if t, err = time.Parse(time.RFC3339, value.Text); err != nil {
+		return from, to, c.errorf(value.Pos, "Invalid date %q, expected e.g. 7d or 2006-01-02", value.Text)
+	}
+
+	switch {
+	case term.Op == ">":
+		return t, to, nil
+	case term.Op == "<":
+		return from, t, nil
+	case day:
+		return t, t.AddDate(0, 0, 1), nil
+	default:
+		return t, to, nil
+	}
+}
@@ -0,0 +1,140 @@
+package querylang
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.showmax.cc/phabricator"
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+type fakeResolver struct{}
+
+func (fakeResolver) ViewerPHID(ctx context.Context) (string, error) {
+	return "PHID-USER-me", nil
+}
+
+func (fakeResolver) PHIDs(ctx context.Context, names ...string) (map[string]string, error) {
+	known := map[string]string{
+		"@alice":   "PHID-USER-alice",
+		"#backend": "PHID-PROJ-backend",
+		"rXYZ":     "PHID-REPO-xyz",
+		"rRELEASE": "PHID-REPO-release",
+		"R123":     "PHID-REPO-123",
+	}
+	phids := make(map[string]string)
+	for _, name := range names {
+		if phid, found := known[name]; found {
+			phids[name] = phid
+		}
+	}
+	return phids, nil
+}
+
+func (fakeResolver) TicketEnums(ctx context.Context) (*phabricator.TicketEnums, error) {
+	return &phabricator.TicketEnums{
+		Statuses: []phabTypes.TicketStatusInfo{{Value: "open"}, {Value: "resolved"}},
+		Priorities: []phabTypes.TicketPriorityInfo{
+			{Value: 80, Name: "High", Keywords: []string{"high"}},
+			{Value: 50, Name: "Normal", Keywords: []string{"normal"}},
+		},
+	}, nil
+}
+
+func testCompiler() *Compiler {
+	compiler := NewCompiler(fakeResolver{})
+	compiler.Now = func() time.Time { return time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC) }
+	return compiler
+}
+
+func TestCompileTickets(t *testing.T) {
+	args, err := testCompiler().Tickets(context.Background(),
+		`status:open owner:@me,alice project:#backend created:>7d priority:high "login page" crash`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := args.Constraints
+	if len(c.Statuses) != 1 || c.Statuses[0] != phabTypes.TicketStatusOpen {
+		t.Errorf("Unexpected statuses %v", c.Statuses)
+	}
+	if len(c.Assigned) != 2 || c.Assigned[0] != "PHID-USER-me" || c.Assigned[1] != "PHID-USER-alice" {
+		t.Errorf("Unexpected owners %v", c.Assigned)
+	}
+	if len(c.Projects) != 1 || c.Projects[0] != "PHID-PROJ-backend" {
+		t.Errorf("Unexpected projects %v", c.Projects)
+	}
+	if c.CreatedStart != time.Date(2020, 6, 8, 12, 0, 0, 0, time.UTC).Unix() || c.CreatedEnd != 0 {
+		t.Errorf("Unexpected created range %d - %d", c.CreatedStart, c.CreatedEnd)
+	}
+	if len(c.Priorities) != 1 || c.Priorities[0] != phabTypes.TicketPriorityHigh {
+		t.Errorf("Unexpected priorities %v", c.Priorities)
+	}
+	if c.Query != `"login page" crash` {
+		t.Errorf("Unexpected full-text query %q", c.Query)
+	}
+
+	args, err = testCompiler().Tickets(context.Background(), `owner:none closed:2020-06-01`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.Constraints.Assigned[0] != "null()" || args.Constraints.ClosedEnd-args.Constraints.ClosedStart != 24*60*60 {
+		t.Errorf("Unexpected constraints %+v", args.Constraints)
+	}
+}
+
+func TestCompileRevisions(t *testing.T) {
+	args, err := testCompiler().Revisions(context.Background(), `status:needs-review reviewer:me repo:XYZ id:D12,13`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := args.Constraints
+	if c.Statuses[0] != phabTypes.RevisionStatusNeedsReview || c.ReviewerPHIDs[0] != "PHID-USER-me" || c.RepositoryPHIDs[0] != "PHID-REPO-xyz" {
+		t.Errorf("Unexpected constraints %+v", c)
+	}
+	if len(c.Ids) != 2 || c.Ids[0] != 12 || c.Ids[1] != 13 {
+		t.Errorf("Unexpected IDs %v", c.Ids)
+	}
+}
+
+func TestCompileRepositories(t *testing.T) {
+	for query, phid := range map[string]string{
+		`repo:XYZ`:     "PHID-REPO-xyz",
+		`repo:rXYZ`:    "PHID-REPO-xyz",
+		`repo:RELEASE`: "PHID-REPO-release",
+		`repo:R123`:    "PHID-REPO-123",
+	} {
+		args, err := testCompiler().Revisions(context.Background(), query)
+		if err != nil {
+			t.Errorf("%s: %s", query, err)
+			continue
+		}
+		if repos := args.Constraints.RepositoryPHIDs; len(repos) != 1 || repos[0] != phid {
+			t.Errorf("%s resolved to %v", query, repos)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for query, pos := range map[string]int{
+		`status:opne`:              7,
+		`owner:me,bob`:             9,
+		`project:#frontend`:        8,
+		`priority:urgent`:          9,
+		`created:>yesterday`:       9,
+		`crash colour:red`:         6,
+		`order:priority,id`:        15,
+		`status:open id:T1,Tx`:     18,
+		`created:<7d created:<<1d`: 21,
+	} {
+		_, err := testCompiler().Tickets(context.Background(), query)
+		qerr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%q: expected *Error, got %v", query, err)
+			continue
+		}
+		if qerr.Pos != pos {
+			t.Errorf("%q: error at %d, expected %d:\n%s", query, qerr.Pos, pos, qerr.Pointer())
+		}
+	}
+}
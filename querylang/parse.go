@@ -0,0 +1,128 @@
+// Package querylang compiles human-written search queries such as
+//
+//	status:open owner:@me project:#backend created:>7d priority:high
+//
+// into search arguments, e.g. TicketSearchArgs. Terms are separated by
+// spaces, a term is either key:value or a word for full-text search.
+// Several values are separated by commas, values with spaces are quoted.
+package querylang
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error is a problem found in a query. Pos is the byte offset of the
+// offending part, so it can be pointed at.
+type Error struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Query error at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// Pointer returns the query with a caret under the offending part
+func (e *Error) Pointer() string {
+	return e.Query + "\n" + strings.Repeat(" ", e.Pos) + "^"
+}
+
+// Value is a single value of a term
+type Value struct {
+	Text string
+	// Byte offset of the value in the query
+	Pos int
+	// Whether the value was quoted
+	Quoted bool
+}
+
+// Term is a single key:value or full-text term of a query
+type Term struct {
+	// Lowercase key, empty for full-text words
+	Key string
+	// Comparison of ranges: ">", "<" or empty
+	Op     string
+	Values []Value
+	// Byte offset of the term in the query
+	Pos int
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isKeyChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '-' || c == '_'
+}
+
+// Parse splits Q into terms
+func Parse(q string) ([]Term, error) {
+	var terms []Term
+	i := 0
+	for i < len(q) {
+		if isSpace(q[i]) {
+			i++
+			continue
+		}
+		term := Term{Pos: i}
+		j := i
+		for j < len(q) && isKeyChar(q[j]) {
+			j++
+		}
+		if j > i && j < len(q) && q[j] == ':' {
+			term.Key = strings.ToLower(q[i:j])
+			i = j + 1
+			if i < len(q) && (q[i] == '>' || q[i] == '<') {
+				term.Op = q[i : i+1]
+				i++
+			}
+		}
+		for {
+			value, next, err := scanValue(q, i, term.Key != "")
+			if err != nil {
+				return nil, err
+			}
+			if value.Text == "" && !value.Quoted {
+				return nil, &Error{Query: q, Pos: i, Msg: fmt.Sprintf("Missing value for %q", term.Key)}
+			}
+			term.Values = append(term.Values, value)
+			i = next
+			if term.Key == "" || i >= len(q) || q[i] != ',' {
+				break
+			}
+			i++
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// scanValue reads a single value starting at I. Commas end values
+// of keyed terms, full-text words keep them.
+func scanValue(q string, i int, keyed bool) (Value, int, error) {
+	value := Value{Pos: i}
+	if i < len(q) && q[i] == '"' {
+		end := strings.IndexByte(q[i+1:], '"')
+		if end < 0 {
+			return value, 0, &Error{Query: q, Pos: i, Msg: "Unterminated quote"}
+		}
+		value.Text = q[i+1 : i+1+end]
+		value.Quoted = true
+		next := i + end + 2
+		if next < len(q) && !isSpace(q[next]) && !(keyed && q[next] == ',') {
+			return value, 0, &Error{Query: q, Pos: next, Msg: "Expected a space after the quoted value"}
+		}
+		return value, next, nil
+	}
+	j := i
+	for j < len(q) && !isSpace(q[j]) && !(keyed && q[j] == ',') {
+		if q[j] == '"' {
+			return value, 0, &Error{Query: q, Pos: j, Msg: "Unexpected quote inside a value"}
+		}
+		j++
+	}
+	value.Text = q[i:j]
+	return value, j, nil
+}
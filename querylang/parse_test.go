@@ -0,0 +1,45 @@
+package querylang
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	terms, err := Parse(`status:open,resolved owner:@me created:>7d "exact phrase" crash title:"a b"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(terms) != 6 {
+		t.Fatalf("Expected 6 terms, got %+v", terms)
+	}
+	if terms[0].Key != "status" || len(terms[0].Values) != 2 || terms[0].Values[1].Text != "resolved" || terms[0].Values[1].Pos != 12 {
+		t.Errorf("Unexpected status term %+v", terms[0])
+	}
+	if terms[2].Op != ">" || terms[2].Values[0].Text != "7d" {
+		t.Errorf("Unexpected created term %+v", terms[2])
+	}
+	if terms[3].Key != "" || !terms[3].Values[0].Quoted || terms[3].Values[0].Text != "exact phrase" {
+		t.Errorf("Unexpected phrase %+v", terms[3])
+	}
+	if terms[5].Values[0].Text != "a b" {
+		t.Errorf("Unexpected quoted value %+v", terms[5])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for query, pos := range map[string]int{
+		`status:`:         7,
+		`status:open,`:    12,
+		`title:"unclosed`: 6,
+		`title:"a"b`:      9,
+		`owner:al"ice`:    8,
+	} {
+		_, err := Parse(query)
+		qerr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%q: expected *Error, got %v", query, err)
+			continue
+		}
+		if qerr.Pos != pos {
+			t.Errorf("%q: error at %d, expected %d: %s", query, qerr.Pos, pos, qerr)
+		}
+	}
+}
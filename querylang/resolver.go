@@ -0,0 +1,55 @@
+package querylang
+
+import (
+	"context"
+	"time"
+
+	"go.showmax.cc/phabricator"
+)
+
+// Resolver looks up the objects queries refer to
+type Resolver interface {
+	// ViewerPHID returns the PHID of the user @me stands for
+	ViewerPHID(ctx context.Context) (string, error)
+	// PHIDs resolves monograms such as @alice, #backend or rXYZ.
+	// Unknown names are missing from the result.
+	PHIDs(ctx context.Context, names ...string) (map[string]string, error)
+	// TicketEnums returns the task statuses and priorities of the instance
+	TicketEnums(ctx context.Context) (*phabricator.TicketEnums, error)
+}
+
+// phabResolver resolves names using a Phabricator instance
+type phabResolver struct {
+	phab      *phabricator.Phabricator
+	monograms *phabricator.MonogramResolver
+}
+
+// NewResolver creates a Resolver asking PHAB. Resolved names are cached
+// for TTL, a zero TTL caches them for the resolver's lifetime.
+func NewResolver(phab *phabricator.Phabricator, ttl time.Duration) Resolver {
+	return &phabResolver{phab: phab, monograms: phabricator.NewMonogramResolver(phab, ttl)}
+}
+
+func (r *phabResolver) ViewerPHID(ctx context.Context) (string, error) {
+	viewer, err := r.phab.Viewer(ctx)
+	if err != nil {
+		return "", err
+	}
+	return viewer.PHID, nil
+}
+
+func (r *phabResolver) PHIDs(ctx context.Context, names ...string) (map[string]string, error) {
+	handles, err := r.monograms.Resolve(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	phids := make(map[string]string, len(handles))
+	for name, handle := range handles {
+		phids[name] = handle.PHID
+	}
+	return phids, nil
+}
+
+func (r *phabResolver) TicketEnums(ctx context.Context) (*phabricator.TicketEnums, error) {
+	return r.phab.TicketEnums(ctx)
+}
@@ -0,0 +1,103 @@
+package phabricator
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// Every credential the library gets hold of is registered here,
+// so that it can be scrubbed from anything that leaves the library -
+// log messages, log fields and errors
+var secrets = struct {
+	sync.RWMutex
+	values map[string]bool
+}{values: make(map[string]bool)}
+
+func registerSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.values[secret] = true
+	// Secrets also travel form-encoded
+	secrets.values[url.QueryEscape(secret)] = true
+}
+
+// Redact replaces all credentials known to the library in S
+// with [REDACTED]. Use it before printing anything derived
+// from raw requests or responses.
+func Redact(s string) string {
+	secrets.RLock()
+	defer secrets.RUnlock()
+	for secret := range secrets.values {
+		if strings.Contains(s, secret) {
+			s = strings.Replace(s, secret, redacted, -1)
+		}
+	}
+	return s
+}
+
+// redactHook scrubs credentials from every log entry
+// as the last line of defense
+type redactHook struct{}
+
+func (redactHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (redactHook) Fire(entry *log.Entry) error {
+	entry.Message = Redact(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = Redact(v)
+		case error:
+			if msg := v.Error(); Redact(msg) != msg {
+				entry.Data[key] = Redact(msg)
+			}
+		default:
+			if s := fmt.Sprint(v); Redact(s) != s {
+				entry.Data[key] = Redact(s)
+			}
+		}
+	}
+	return nil
+}
+
+// redactedError wraps errors whose message contained a credential
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError makes sure ERR's message doesn't contain any credentials
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if clean := Redact(msg); clean != msg {
+		return &redactedError{msg: clean, err: err}
+	}
+	return err
+}
+
+func init() {
+	logger.AddHook(redactHook{})
+}
@@ -0,0 +1,126 @@
+package phabricator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// echoServer answers like Phabricator would, but echoes the raw request
+// body in every error, which is the worst case for leaking credentials
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/api/conduit.query":
+			fmt.Fprint(w, `{"result": {"maniphest.search": {"params":[]}, "maniphest.edit": {"params":[]}}}`)
+		case "/api/maniphest.search":
+			fmt.Fprint(w, `{"result": {"data": [{"id": 1, "phid": "PHID-TASK-1"}], "cursor": {"after": null}}}`)
+		default:
+			fmt.Fprintf(w, `{"error_code": "ERR-CONDUIT-CORE", "error_info": %q}`, string(body))
+		}
+	}))
+}
+
+func assertRedacted(t *testing.T, what, text string, secrets ...string) {
+	t.Helper()
+	for _, secret := range secrets {
+		if strings.Contains(text, secret) {
+			t.Errorf("Secret %q leaked into %s: %s", secret, what, text)
+		}
+	}
+}
+
+func TestTokenNeverLogged(t *testing.T) {
+	const token = "api-s3cr3ttok3nvalue"
+	server := echoServer()
+	defer server.Close()
+
+	var logs bytes.Buffer
+	defer logger.SetOutput(os.Stderr)
+	ctx := context.Background()
+	phab, err := New(ctx,
+		WithAPI(server.URL+"/api/"),
+		WithToken(token),
+		WithLogLevel("trace"),
+		WithLogOutput(&logs),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for result := range phab.CallSearch(ctx, "maniphest.search", phabTypes.TicketSearchArgs{}, phabTypes.Ticket{}) {
+		if err, ok := result.(error); ok {
+			t.Fatal(err)
+		}
+	}
+	args := &EditArguments{
+		ObjectIdentifier: "T1",
+		Transactions:     []PhabTransaction{NewTransaction("title", "Leak?")},
+	}
+	err = phab.CallEdit(ctx, "maniphest.edit", args)
+	if err == nil {
+		t.Fatal("Expected the echo server to reject the edit")
+	}
+	assertRedacted(t, "edit error", err.Error(), token)
+	phab.WhoAmI(ctx)
+
+	if !strings.Contains(logs.String(), "HTTP Request dump") {
+		t.Error("Request dumps not logged at trace level")
+	}
+	assertRedacted(t, "logs", logs.String(), token)
+}
+
+func TestAccessTokenNeverLogged(t *testing.T) {
+	const accessToken = "oauthaccesstokenvalue"
+	server := echoServer()
+	defer server.Close()
+
+	var logs bytes.Buffer
+	defer logger.SetOutput(os.Stderr)
+	ctx := context.Background()
+	phab, err := New(ctx,
+		WithAPI(server.URL+"/api/"),
+		WithAccessToken(accessToken),
+		WithLogLevel("debug"),
+		WithLogOutput(&logs),
+		WithEndpoints("maniphest.edit"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = phab.CallEdit(ctx, "maniphest.edit", &EditArguments{ObjectIdentifier: 1})
+	if err == nil {
+		t.Fatal("Expected the echo server to reject the edit")
+	}
+	assertRedacted(t, "edit error", err.Error(), accessToken)
+	assertRedacted(t, "logs", logs.String(), accessToken)
+}
+
+func TestTransportErrorRedacted(t *testing.T) {
+	const token = "api-transporterrortoken"
+	registerSecret(token)
+	// A URL that embeds the token is the only way it can reach a transport error
+	var phab Phabricator
+	phab.client = http.DefaultClient
+	_, err := phab.post(context.Background(), "http://127.0.0.1:1/api/"+token, "")
+	if err == nil {
+		t.Fatal("Expected a transport error")
+	}
+	assertRedacted(t, "transport error", err.Error(), token)
+}
+
+func TestRedact(t *testing.T) {
+	registerSecret("api-redactme")
+	if got := Redact("api.token=api-redactme&x=1"); got != "api.token=[REDACTED]&x=1" {
+		t.Errorf("Unexpected redaction %q", got)
+	}
+}
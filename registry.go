@@ -0,0 +1,176 @@
+package phabricator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+)
+
+// Registry holds one Phabricator instance per Phabricator install,
+// e.g. production and staging, and picks the right one by name or URL
+type Registry struct {
+	lock       sync.RWMutex
+	clients    map[string]*Phabricator // keyed by API root
+	names      map[string]string       // name -> API root
+	defaultAPI string
+}
+
+// NewRegistry creates an instance for every host in .arcrc. Hosts
+// without a token or certificate are skipped. OPTS are applied to
+// every instance, except for the API root and credentials, which
+// always come from .arcrc; WithToken and WithAccessToken are ignored.
+// Use WithArcrc to read another file. Each instance is created by
+// New, so no requests are made until the instances are used.
+func NewRegistry(ctx context.Context, opts ...Option) (*Registry, error) {
+	var options PhabOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	arcCfg, err := arcConfig(options.Arcrc)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Registry{
+		clients: make(map[string]*Phabricator),
+		names:   make(map[string]string),
+	}
+	for api, host := range arcCfg.Hosts {
+		if host.Token == "" && (host.User == "" || host.Cert == "") {
+			logger.WithField("api", api).Warn("Skipping .arcrc host without credentials")
+			continue
+		}
+		creds := &Credentials{API: api, Token: host.Token, User: host.User, Certificate: host.Cert}
+		hostOpts := append(opts[:len(opts):len(opts)],
+			WithAPI(api),
+			WithToken(""),
+			WithAccessToken(""),
+			WithTokenSource(TokenSourceFunc(func(ctx context.Context, api string) (*Credentials, error) {
+				return creds, nil
+			})),
+		)
+		client, err := New(ctx, hostOpts...)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				"error": err,
+				"api":   api,
+			}).Error("Unable to create a Phabricator instance")
+			return nil, err
+		}
+		if err := r.Add(client); err != nil {
+			return nil, err
+		}
+	}
+
+	if hostURI := defaultHostURI(arcCfg); hostURI != "" {
+		if api, err := apiURI(hostURI); err == nil {
+			if _, found := r.clients[api]; found {
+				r.defaultAPI = api
+			}
+		}
+	}
+	return r, nil
+}
+
+// Add registers CLIENT. It can be looked up by its API root,
+// the root of the Phabricator install and its host name.
+func (r *Registry) Add(client *Phabricator) error {
+	api := client.ConduitURI()
+	if api == "" {
+		return errors.New("Can't register an uninitialized Phabricator instance")
+	}
+	apiURL, err := url.Parse(api)
+	if err != nil {
+		return err
+	}
+	root, _ := url.Parse("/")
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.clients == nil {
+		r.clients = make(map[string]*Phabricator)
+		r.names = make(map[string]string)
+	}
+	r.clients[api] = client
+	r.names[api] = api
+	r.names[apiURL.ResolveReference(root).String()] = api
+	r.names[apiURL.Host] = api
+	return nil
+}
+
+// Alias makes the instance of API root API available under NAME too,
+// e.g. "production" or "staging"
+func (r *Registry) Alias(name, api string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, found := r.clients[api]; !found {
+		return fmt.Errorf("No Phabricator instance for %s", api)
+	}
+	r.names[name] = api
+	return nil
+}
+
+// Hosts returns the API roots of all registered instances
+func (r *Registry) Hosts() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	hosts := make([]string, 0, len(r.clients))
+	for api := range r.clients {
+		hosts = append(hosts, api)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Default returns the instance of the default host, as arc would pick it
+func (r *Registry) Default() (*Phabricator, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.defaultAPI == "" {
+		return nil, errors.New("No default Phabricator host configured")
+	}
+	return r.clients[r.defaultAPI], nil
+}
+
+// Get returns the instance registered under NAME, which is an alias,
+// a host name, or any URL on the Phabricator install
+func (r *Registry) Get(name string) (*Phabricator, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if api, found := r.names[name]; found {
+		return r.clients[api], nil
+	}
+	if u, err := url.Parse(name); err == nil && u.Host != "" {
+		if api, found := r.names[u.Host]; found {
+			return r.clients[api], nil
+		}
+	}
+	return nil, fmt.Errorf("No Phabricator instance for %s", name)
+}
+
+// Route picks the instance an object URL such as https://phab.example.com/T123
+// belongs to and returns it along with the monogram of the object ("T123"),
+// see ParseObjectURL. Anything that isn't a URL is looked up in the
+// default instance.
+func (r *Registry) Route(objectURL string) (*Phabricator, string, error) {
+	u, err := url.Parse(objectURL)
+	if err != nil || u.Host == "" {
+		client, err := r.Default()
+		return client, objectURL, err
+	}
+	client, err := r.Get(u.Host)
+	if err != nil {
+		return nil, "", err
+	}
+	monogram, err := ParseObjectURL(objectURL)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, monogram.String(), nil
+}
@@ -0,0 +1,76 @@
+package phabricator
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const registryArcrc = `{
+	"hosts": {
+		"https://phab.example.com/api/": {"token": "api-production"},
+		"https://phab-staging.example.com/api/": {"token": "api-staging"},
+		"https://phab-dev.example.com/api/": {}
+	},
+	"config": {"default": "https://phab.example.com/"}
+}`
+
+func TestRegistry(t *testing.T) {
+	registry, err := NewRegistry(context.Background(),
+		WithArcrc(strings.NewReader(registryArcrc)),
+		WithLogLevel("error"),
+		// Must not replace the credentials of each host
+		WithToken("api-other"),
+		WithAccessToken("oauth-other"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hosts := registry.Hosts(); len(hosts) != 2 {
+		t.Fatalf("Expected two hosts with credentials, got %v", hosts)
+	}
+
+	staging, err := registry.Get("phab-staging.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if staging.ConduitURI() != "https://phab-staging.example.com/api/" || staging.apiToken != "api-staging" || staging.accessToken != "" {
+		t.Errorf("Wrong instance for staging: %s", staging.ConduitURI())
+	}
+	if err := registry.Alias("staging", staging.ConduitURI()); err != nil {
+		t.Fatal(err)
+	}
+	if byAlias, _ := registry.Get("staging"); byAlias != staging {
+		t.Error("Alias doesn't resolve to the staging instance")
+	}
+
+	production, err := registry.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if production.apiToken != "api-production" {
+		t.Error("Default instance isn't production")
+	}
+
+	client, object, err := registry.Route("https://phab-staging.example.com/T123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client != staging || object != "T123" {
+		t.Errorf("Unexpected route to %s, %q", client.ConduitURI(), object)
+	}
+	client, object, err = registry.Route("D45")
+	if err != nil || client != production || object != "D45" {
+		t.Errorf("Monogram not routed to the default instance")
+	}
+	client, object, err = registry.Route("https://phab-staging.example.com/p/alice/")
+	if err != nil || client != staging || object != "@alice" {
+		t.Errorf("Unexpected route of a user URL: %q, %v", object, err)
+	}
+	if _, _, err := registry.Route("https://phab-staging.example.com/maniphest/query/all/"); err == nil {
+		t.Error("URL without an object routed")
+	}
+	if _, _, err := registry.Route("https://unknown.example.com/T1"); err == nil {
+		t.Error("Unknown host routed")
+	}
+}
@@ -0,0 +1,270 @@
+package phabricator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Environment variable EnvTokenSource reads the token from by default
+	DefaultTokenEnv = "PHABRICATOR_TOKEN"
+	// Environment variable EnvTokenSource reads the API root from by default
+	DefaultAPIEnv = "PHABRICATOR_API"
+)
+
+// ErrNoToken is returned by a TokenSource that has no credentials
+// for the requested API. ChainTokenSource moves on to the next source.
+var ErrNoToken = errors.New("No token available")
+
+// Credentials authenticate requests to a single Conduit API root.
+// Either AccessToken, Token, or the legacy User and Certificate pair is set.
+type Credentials struct {
+	API string
+	// Conduit API token, sent as api.token
+	Token string
+	// OAuth access token, sent as access_token
+	AccessToken string
+	User        string
+	Certificate string
+}
+
+// TokenSource provides Conduit credentials. API is the requested API root.
+// If it's empty, the source may pick a default one and report it in
+// the returned Credentials. Errors wrapping ErrNoToken mean the source
+// has nothing to offer, any other error is fatal.
+type TokenSource interface {
+	Credentials(ctx context.Context, api string) (*Credentials, error)
+}
+
+// TokenSourceFunc adapts a function to TokenSource, e.g. to fetch
+// tokens from a secret manager
+type TokenSourceFunc func(ctx context.Context, api string) (*Credentials, error)
+
+// Credentials calls f(ctx, api)
+func (f TokenSourceFunc) Credentials(ctx context.Context, api string) (*Credentials, error) {
+	return f(ctx, api)
+}
+
+// StaticTokenSource always returns the same token. If API is set,
+// the token is only offered for that API root.
+type StaticTokenSource struct {
+	API   string
+	Token string
+}
+
+// Credentials implements TokenSource
+func (s StaticTokenSource) Credentials(ctx context.Context, api string) (*Credentials, error) {
+	return matchAPI("static token", s.API, api, s.Token)
+}
+
+// EnvTokenSource reads the token and optionally the API root from
+// environment variables. Empty names mean DefaultTokenEnv and DefaultAPIEnv.
+// A token without an API root is only offered for DefaultAPI, so that
+// it isn't sent to every instance the caller connects to.
+type EnvTokenSource struct {
+	TokenVar string
+	APIVar   string
+	// API root of the default host, see defaultArcAPI. Tokens without
+	// an API root are skipped if it's empty.
+	DefaultAPI string
+}
+
+// Credentials implements TokenSource
+func (s EnvTokenSource) Credentials(ctx context.Context, api string) (*Credentials, error) {
+	tokenVar, apiVar := s.TokenVar, s.APIVar
+	if tokenVar == "" {
+		tokenVar = DefaultTokenEnv
+	}
+	if apiVar == "" {
+		apiVar = DefaultAPIEnv
+	}
+	tokenAPI := os.Getenv(apiVar)
+	if tokenAPI == "" && os.Getenv(tokenVar) != "" {
+		if s.DefaultAPI == "" {
+			return nil, fmt.Errorf("%w: $%s is not set and there's no default host", ErrNoToken, apiVar)
+		}
+		tokenAPI = s.DefaultAPI
+	}
+	return matchAPI("$"+tokenVar, tokenAPI, api, os.Getenv(tokenVar))
+}
+
+// FileTokenSource reads the token from a file, e.g. a mounted secret.
+// Surrounding whitespace is ignored. If API is set, the token is
+// only offered for that API root.
+type FileTokenSource struct {
+	Path string
+	API  string
+}
+
+// Credentials implements TokenSource
+func (s FileTokenSource) Credentials(ctx context.Context, api string) (*Credentials, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s doesn't exist", ErrNoToken, s.Path)
+	} else if err != nil {
+		logger.WithFields(log.Fields{
+			"error": err,
+			"path":  s.Path,
+		}).Error("Unable to read token file")
+		return nil, err
+	}
+	return matchAPI(s.Path, s.API, api, strings.TrimSpace(string(data)))
+}
+
+// matchAPI offers TOKEN from a source bound to SOURCEAPI for REQUESTEDAPI
+func matchAPI(source, sourceAPI, requestedAPI, token string) (*Credentials, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%w: %s is empty", ErrNoToken, source)
+	}
+	if requestedAPI == "" {
+		if sourceAPI == "" {
+			return nil, fmt.Errorf("%w: %s is not bound to any API", ErrNoToken, source)
+		}
+		return &Credentials{API: sourceAPI, Token: token}, nil
+	}
+	if sourceAPI != "" && sourceAPI != requestedAPI {
+		return nil, fmt.Errorf("%w: %s is bound to %s", ErrNoToken, source, sourceAPI)
+	}
+	return &Credentials{API: requestedAPI, Token: token}, nil
+}
+
+// ArcrcTokenSource reads credentials from .arcrc the way arc does.
+// Without a requested API, the default host is used.
+type ArcrcTokenSource struct {
+	// Alternate file to read from. If nil, will read $ARC_CONFIG or ~/.arcrc
+	Arcrc io.Reader
+}
+
+// Credentials implements TokenSource
+func (s ArcrcTokenSource) Credentials(ctx context.Context, api string) (*Credentials, error) {
+	if s.Arcrc == nil {
+		arcrcPath, err := arcUserConfigPath()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(arcrcPath); os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s doesn't exist", ErrNoToken, arcrcPath)
+		}
+	}
+	arcCfg, err := arcConfig(s.Arcrc)
+	if err != nil {
+		return nil, err
+	}
+
+	if api == "" {
+		hostURI := defaultHostURI(arcCfg)
+		if hostURI == "" {
+			return nil, fmt.Errorf(`%w: can't determine a default host to connect to. See
+https://www.mediawiki.org/w/index.php?title=Phabricator/Arcanist#Setup for
+details`, ErrNoToken)
+		}
+		if api, err = apiURI(hostURI); err != nil {
+			return nil, err
+		}
+	}
+
+	host, found := arcCfg.Hosts[api]
+	if !found {
+		return nil, fmt.Errorf("%w: no .arcrc entry for %s", ErrNoToken, api)
+	}
+	if host.Token == "" && (host.User == "" || host.Cert == "") {
+		return nil, fmt.Errorf("%w: no token or certificate in .arcrc for %s", ErrNoToken, api)
+	}
+	return &Credentials{
+		API:         api,
+		Token:       host.Token,
+		User:        host.User,
+		Certificate: host.Cert,
+	}, nil
+}
+
+// ChainTokenSource tries its sources in order and returns
+// the first credentials found
+type ChainTokenSource []TokenSource
+
+// Credentials implements TokenSource
+func (c ChainTokenSource) Credentials(ctx context.Context, api string) (*Credentials, error) {
+	reasons := make([]string, 0, len(c))
+	for _, source := range c {
+		creds, err := source.Credentials(ctx, api)
+		if err == nil {
+			return creds, nil
+		}
+		if !errors.Is(err, ErrNoToken) {
+			return nil, err
+		}
+		logger.WithError(err).Debug("Token source has no credentials")
+		reasons = append(reasons, err.Error())
+	}
+	return nil, fmt.Errorf("%w for %q (%s)", ErrNoToken, api, strings.Join(reasons, "; "))
+}
+
+// defaultArcAPI returns the API root of the host arc would talk to,
+// see defaultHostURI, or "" if there's none. ARCRC is read instead
+// of $ARC_CONFIG or ~/.arcrc if set.
+func defaultArcAPI(arcrc io.Reader) string {
+	if arcrc == nil {
+		arcrcPath, err := arcUserConfigPath()
+		if err != nil {
+			return ""
+		}
+		if _, err := os.Stat(arcrcPath); os.IsNotExist(err) {
+			// .arcconfig or the system config may still name a default
+			arcrc = strings.NewReader("{}")
+		}
+	}
+	arcCfg, err := arcConfig(arcrc)
+	if err != nil {
+		return ""
+	}
+	hostURI := defaultHostURI(arcCfg)
+	if hostURI == "" {
+		return ""
+	}
+	api, err := apiURI(hostURI)
+	if err != nil {
+		return ""
+	}
+	return api
+}
+
+// defaultTokenSource is used if PhabOptions.TokenSource is nil
+func defaultTokenSource(opts *PhabOptions) (TokenSource, error) {
+	if opts.AccessToken != "" {
+		return TokenSourceFunc(func(ctx context.Context, api string) (*Credentials, error) {
+			return &Credentials{API: api, AccessToken: opts.AccessToken}, nil
+		}), nil
+	}
+	if opts.Token != "" {
+		return StaticTokenSource{API: opts.API, Token: opts.Token}, nil
+	}
+	env := EnvTokenSource{}
+	arcrc := opts.Arcrc
+	if os.Getenv(DefaultTokenEnv) != "" && os.Getenv(DefaultAPIEnv) == "" {
+		if arcrc != nil {
+			// Both the default host and ArcrcTokenSource need it
+			data, err := ioutil.ReadAll(arcrc)
+			if err != nil {
+				logger.WithError(err).Error("Unable to read .arcrc")
+				return nil, err
+			}
+			env.DefaultAPI = defaultArcAPI(bytes.NewReader(data))
+			arcrc = bytes.NewReader(data)
+		} else {
+			env.DefaultAPI = defaultArcAPI(nil)
+		}
+	}
+	return ChainTokenSource{
+		env,
+		ArcrcTokenSource{Arcrc: arcrc},
+	}, nil
+}
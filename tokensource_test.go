@@ -0,0 +1,119 @@
+package phabricator
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testAPI = "https://phab.example.com/api/"
+
+func TestChainTokenSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phabricator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("api-from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	chain := ChainTokenSource{
+		EnvTokenSource{TokenVar: "PHABRICATOR_TEST_UNSET_TOKEN"},
+		FileTokenSource{Path: filepath.Join(dir, "missing")},
+		FileTokenSource{Path: tokenFile},
+		StaticTokenSource{API: testAPI, Token: "api-static"},
+	}
+	creds, err := chain.Credentials(context.Background(), testAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Token != "api-from-file" || creds.API != testAPI {
+		t.Errorf("Unexpected credentials %+v", creds)
+	}
+
+	// The file isn't bound to any API, so it can't supply a default
+	creds, err = chain.Credentials(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Token != "api-static" || creds.API != testAPI {
+		t.Errorf("Unexpected default credentials %+v", creds)
+	}
+}
+
+func TestChainTokenSourceExhausted(t *testing.T) {
+	chain := ChainTokenSource{
+		StaticTokenSource{API: "https://other.example.com/api/", Token: "api-other"},
+		ArcrcTokenSource{Arcrc: strings.NewReader(`{"hosts": {}}`)},
+	}
+	_, err := chain.Credentials(context.Background(), testAPI)
+	if !errors.Is(err, ErrNoToken) {
+		t.Errorf("Expected ErrNoToken, got %v", err)
+	}
+}
+
+func TestChainTokenSourceFatalError(t *testing.T) {
+	fatal := errors.New("secret store unavailable")
+	chain := ChainTokenSource{
+		TokenSourceFunc(func(ctx context.Context, api string) (*Credentials, error) {
+			return nil, fatal
+		}),
+		StaticTokenSource{Token: "api-static"},
+	}
+	if _, err := chain.Credentials(context.Background(), testAPI); err != fatal {
+		t.Errorf("Expected the chain to stop on a fatal error, got %v", err)
+	}
+}
+
+func TestArcrcTokenSourceCertificate(t *testing.T) {
+	arcrc := `{"hosts": {"https://phab.example.com/api/": {"user": "alice", "cert": "certificate"}}}`
+	source := ArcrcTokenSource{Arcrc: strings.NewReader(arcrc)}
+	creds, err := source.Credentials(context.Background(), testAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var phab Phabricator
+	if err := phab.setCredentials(creds); err != nil {
+		t.Fatal(err)
+	}
+	if phab.certUser != "alice" || phab.certificate != "certificate" {
+		t.Error("Certificate credentials not used")
+	}
+}
+
+func TestDefaultTokenSourceUnboundEnv(t *testing.T) {
+	t.Setenv(ArcSystemConfigEnv, filepath.Join(t.TempDir(), "arcconfig"))
+	t.Setenv(DefaultTokenEnv, "api-from-env")
+	t.Setenv(DefaultAPIEnv, "")
+	arcrc := `{
+		"config": {"default": "https://phab.example.com/"},
+		"hosts": {"https://other.example.com/api/": {"token": "api-from-arcrc"}}
+	}`
+	source, err := defaultTokenSource(&PhabOptions{Arcrc: strings.NewReader(arcrc)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := source.Credentials(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Token != "api-from-env" || creds.API != testAPI {
+		t.Errorf("Unexpected default credentials %+v", creds)
+	}
+
+	// The token isn't bound to other.example.com, so .arcrc is used
+	creds, err = source.Credentials(context.Background(), "https://other.example.com/api/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Token != "api-from-arcrc" {
+		t.Errorf("Environment token offered for another host: %+v", creds)
+	}
+}
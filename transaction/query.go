@@ -0,0 +1,53 @@
+// Package transaction builds arguments for transaction.search
+//
+//	args := transaction.Query("T123").
+//		AuthoredBy(me).
+//		Types(phabTypes.TransactionTypeStatus, phabTypes.TransactionTypeComment).
+//		Args()
+//	history, err := phab.Transactions(ctx, args)
+package transaction
+
+import (
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// Endpoint the built arguments are meant for
+const Endpoint = "transaction.search"
+
+// Builder builds TransactionSearchArgs. Every method modifies the builder
+// and returns it, so calls can be chained.
+type Builder struct {
+	args phabTypes.TransactionSearchArgs
+}
+
+// Query starts a query of the history of OBJECT, a PHID or a monogram
+// such as T123. It may be empty if PHIDs are given.
+func Query(object string) *Builder {
+	b := &Builder{}
+	b.args.ObjectIdentifier = object
+	return b
+}
+
+// Args returns the built arguments
+func (b *Builder) Args() phabTypes.TransactionSearchArgs {
+	return b.args
+}
+
+// PHIDs limits the results to transactions with the given PHIDs
+func (b *Builder) PHIDs(phids ...string) *Builder {
+	b.args.Constraints.Phids = append(b.args.Constraints.Phids, phids...)
+	return b
+}
+
+// AuthoredBy limits the results to transactions made by the given users
+func (b *Builder) AuthoredBy(phids ...string) *Builder {
+	b.args.Constraints.AuthorPHIDs = append(b.args.Constraints.AuthorPHIDs, phids...)
+	return b
+}
+
+// Types limits the results to transactions of the given types.
+// The filter is applied by Phabricator.Transactions, not by Conduit.
+func (b *Builder) Types(types ...phabTypes.TransactionType) *Builder {
+	b.args.Types = append(b.args.Types, types...)
+	return b
+}
@@ -0,0 +1,31 @@
+package transaction
+
+import (
+	"testing"
+
+	query "github.com/google/go-querystring/query"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+func TestQuery(t *testing.T) {
+	args := Query("T123").
+		AuthoredBy("PHID-USER-me").
+		Types(phabTypes.TransactionTypeStatus).
+		Args()
+	values, err := query.Values(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values.Get("objectIdentifier") != "T123" || values.Get("constraints[authorPHIDs][]") != "PHID-USER-me" {
+		t.Errorf("Unexpected arguments %v", values)
+	}
+	for key := range values {
+		if key == "Types" || key == "types" {
+			t.Errorf("Client-side type filter sent to Conduit: %v", values)
+		}
+	}
+	if len(args.Types) != 1 || args.Types[0] != phabTypes.TransactionTypeStatus {
+		t.Errorf("Unexpected types %v", args.Types)
+	}
+}
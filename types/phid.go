@@ -0,0 +1,78 @@
+package phabricator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// PHID is a Phabricator object identifier, e.g. PHID-TASK-cx3ldq3bvj2ba3h4tkvf.
+// The part after the PHID- prefix names the application type of the object.
+type PHID string
+
+var phidRegex = regexp.MustCompile(`^PHID-([A-Z0-9]{4})-\S+$`)
+
+// ParsePHID validates S and converts it to PHID
+func ParsePHID(s string) (PHID, error) {
+	phid := PHID(s)
+	if !phid.Valid() {
+		return "", fmt.Errorf("Invalid PHID %q", s)
+	}
+	return phid, nil
+}
+
+// Valid reports whether p is a well-formed PHID
+func (p PHID) Valid() bool {
+	return phidRegex.MatchString(string(p))
+}
+
+// Type returns the 4-letter application type, e.g. TASK or USER,
+// or an empty string for an invalid PHID
+func (p PHID) Type() string {
+	match := phidRegex.FindStringSubmatch(string(p))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func (p PHID) String() string {
+	return string(p)
+}
+
+// MarshalJSON implements json.Marshaler. An empty PHID is encoded as null,
+// which is what Phabricator returns for e.g. unassigned tasks.
+func (p PHID) MarshalJSON() ([]byte, error) {
+	if p == "" {
+		return []byte("null"), nil
+	}
+	return json.Marshal(string(p))
+}
+
+// UnmarshalJSON implements json.Unmarshaler and rejects malformed PHIDs
+func (p *PHID) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == nil || *s == "" {
+		*p = ""
+		return nil
+	}
+	phid, err := ParsePHID(*s)
+	if err != nil {
+		return err
+	}
+	*p = phid
+	return nil
+}
+
+// EncodeValues implements query.Encoder, so that a PHID
+// can be used in search arguments
+func (p PHID) EncodeValues(key string, v *url.Values) error {
+	if p != "" {
+		v.Add(key, string(p))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package phabricator
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPHIDType(t *testing.T) {
+	for phid, typ := range map[PHID]string{
+		"PHID-TASK-cx3ldq3bvj2ba3h4tkvf":            "TASK",
+		"PHID-USER-abc":                             "USER",
+		"PHID-APPS-PhabricatorManiphestApplication": "APPS",
+		"PHID-TASK-":                                "",
+		"PHID-TASKS-abc":                            "",
+		"T123":                                      "",
+		"":                                          "",
+	} {
+		if got := phid.Type(); got != typ {
+			t.Errorf("%q: expected type %q, got %q", phid, typ, got)
+		}
+		if phid.Valid() != (typ != "") {
+			t.Errorf("%q: unexpected validity", phid)
+		}
+	}
+}
+
+func TestPHIDJSON(t *testing.T) {
+	var owner struct {
+		OwnerPHID PHID `json:"ownerPHID"`
+	}
+	if err := json.Unmarshal([]byte(`{"ownerPHID": null}`), &owner); err != nil || owner.OwnerPHID != "" {
+		t.Errorf("null PHID not decoded as empty: %q (%v)", owner.OwnerPHID, err)
+	}
+	if err := json.Unmarshal([]byte(`{"ownerPHID": "PHID-USER-abc"}`), &owner); err != nil || owner.OwnerPHID.Type() != "USER" {
+		t.Errorf("PHID not decoded: %q (%v)", owner.OwnerPHID, err)
+	}
+	if err := json.Unmarshal([]byte(`{"ownerPHID": "bogus"}`), &owner); err == nil {
+		t.Error("Invalid PHID decoded")
+	}
+	data, err := json.Marshal(owner)
+	if err != nil || string(data) != `{"ownerPHID":"PHID-USER-abc"}` {
+		t.Errorf("Unexpected JSON %s (%v)", data, err)
+	}
+}
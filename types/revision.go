@@ -8,18 +8,20 @@ type RevisionSearchArgs struct {
 		Projects    bool `url:"projects,omitempty"`
 	} `url:"attachments"`
 	Constraints struct {
-		Ids              []int    `url:"ids,omitempty,brackets"`
-		Phids            []string `url:"phids,omitempty,brackets"`
-		ResponsiblePHIDs []string `url:"responsiblePHIDs,omitempty,brackets"`
-		AuthorPHIDs      []string `url:"authorPHIDs,omitempty,brackets"`
-		ReviewerPHIDs    []string `url:"reviewerPHIDs,omitempty,brackets"`
-		RepositoryPHIDs  []string `url:"repositoryPHIDs,omitempty,brackets"`
-		Statuses         []string `url:"statuses,omitempty,brackets"`
-		CreatedStart     int64    `url:"createdStart,omitempty"`
-		CreatedEnd       int64    `url:"createdEnd,omitempty"`
-		Query            string   `url:"query,omitempty"`
-		Subscribers      []string `url:"subscribers,omitempty,brackets"`
-		Projects         []string `url:"projects,omitempty,brackets"`
+		Ids              []int            `url:"ids,omitempty,brackets"`
+		Phids            []string         `url:"phids,omitempty,brackets"`
+		ResponsiblePHIDs []string         `url:"responsiblePHIDs,omitempty,brackets"`
+		AuthorPHIDs      []string         `url:"authorPHIDs,omitempty,brackets"`
+		ReviewerPHIDs    []string         `url:"reviewerPHIDs,omitempty,brackets"`
+		RepositoryPHIDs  []string         `url:"repositoryPHIDs,omitempty,brackets"`
+		Statuses         []RevisionStatus `url:"statuses,omitempty,brackets"`
+		CreatedStart     int64            `url:"createdStart,omitempty"`
+		CreatedEnd       int64            `url:"createdEnd,omitempty"`
+		ModifiedStart    int64            `url:"modifiedStart,omitempty"`
+		ModifiedEnd      int64            `url:"modifiedEnd,omitempty"`
+		Query            string           `url:"query,omitempty"`
+		Subscribers      []string         `url:"subscribers,omitempty,brackets"`
+		Projects         []string         `url:"projects,omitempty,brackets"`
 	} `url:"constraints,omitempty"`
 	Order string `url:"order,omitempty"`
 }
@@ -38,10 +40,10 @@ type Revision struct {
 		Title      string `json:"title"`
 		AuthorPHID string `json:"authorPHID"`
 		Status     struct {
-			Value     string `json:"value"`
-			Name      string `json:"name"`
-			Closed    bool   `json:"closed"`
-			ColorAnsi string `json:"color.ansi"`
+			Value     RevisionStatus `json:"value"`
+			Name      string         `json:"name"`
+			Closed    bool           `json:"closed"`
+			ColorAnsi string         `json:"color.ansi"`
 		} `json:"status"`
 		RepositoryPHID string `json:"repositoryPHID"`
 		DiffPHID       string `json:"diffPHID"`
@@ -0,0 +1,91 @@
+package phabricator
+
+// TicketStatus is the value of a Maniphest task status, e.g. open.
+// Instances can define custom statuses, see maniphest.status.search.
+type TicketStatus string
+
+// Statuses of a stock Phabricator install
+const (
+	TicketStatusOpen      TicketStatus = "open"
+	TicketStatusResolved  TicketStatus = "resolved"
+	TicketStatusWontfix   TicketStatus = "wontfix"
+	TicketStatusInvalid   TicketStatus = "invalid"
+	TicketStatusDuplicate TicketStatus = "duplicate"
+	TicketStatusSpite     TicketStatus = "spite"
+	// Any open status, only valid in search constraints
+	TicketStatusAnyOpen TicketStatus = "open()"
+	// Any closed status, only valid in search constraints
+	TicketStatusAnyClosed TicketStatus = "closed()"
+)
+
+// TicketStatusInfo is a single result of maniphest.status.search
+type TicketStatusInfo struct {
+	Value   TicketStatus `json:"value"`
+	Name    string       `json:"name"`
+	Closed  bool         `json:"closed"`
+	Special string       `json:"special"`
+}
+
+// TicketPriority is the value of a Maniphest task priority, e.g. 80.
+// Instances can define custom priorities, see maniphest.priority.search.
+type TicketPriority int
+
+// Priorities of a stock Phabricator install
+const (
+	TicketPriorityUnbreakNow TicketPriority = 100
+	TicketPriorityTriage     TicketPriority = 90
+	TicketPriorityHigh       TicketPriority = 80
+	TicketPriorityNormal     TicketPriority = 50
+	TicketPriorityLow        TicketPriority = 25
+	TicketPriorityWishlist   TicketPriority = 0
+)
+
+// TicketPriorityInfo is a single result of maniphest.priority.search
+type TicketPriorityInfo struct {
+	Value    TicketPriority `json:"value"`
+	Name     string         `json:"name"`
+	Short    string         `json:"short"`
+	Color    string         `json:"color"`
+	Keywords []string       `json:"keywords"`
+}
+
+// RevisionStatus is the status of a Differential revision, e.g. accepted
+type RevisionStatus string
+
+// Revision statuses. Unlike task statuses, these can't be customized.
+const (
+	RevisionStatusNeedsReview    RevisionStatus = "needs-review"
+	RevisionStatusNeedsRevision  RevisionStatus = "needs-revision"
+	RevisionStatusChangesPlanned RevisionStatus = "changes-planned"
+	RevisionStatusAccepted       RevisionStatus = "accepted"
+	RevisionStatusPublished      RevisionStatus = "published"
+	RevisionStatusAbandoned      RevisionStatus = "abandoned"
+	RevisionStatusDraft          RevisionStatus = "draft"
+	// Any open status, only valid in search constraints
+	RevisionStatusAnyOpen RevisionStatus = "open()"
+	// Any closed status, only valid in search constraints
+	RevisionStatusAnyClosed RevisionStatus = "closed()"
+)
+
+// RevisionStatuses lists all known revision statuses
+var RevisionStatuses = []RevisionStatus{
+	RevisionStatusNeedsReview,
+	RevisionStatusNeedsRevision,
+	RevisionStatusChangesPlanned,
+	RevisionStatusAccepted,
+	RevisionStatusPublished,
+	RevisionStatusAbandoned,
+	RevisionStatusDraft,
+	RevisionStatusAnyOpen,
+	RevisionStatusAnyClosed,
+}
+
+// Valid tells whether S is a known revision status
+func (s RevisionStatus) Valid() bool {
+	for _, known := range RevisionStatuses {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
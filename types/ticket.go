@@ -10,29 +10,29 @@ type TicketSearchArgs struct {
 		Projects    bool `url:"projects,omitempty"`
 	} `url:"attachments"`
 	Constraints struct {
-		Ids           []int    `url:"ids,omitempty,brackets"`
-		Phids         []string `url:"phids,omitempty,brackets"`
-		Assigned      []string `url:"assigned,omitempty,brackets"`
-		AuthorPHIDs   []string `url:"authorPHIDs,omitempty,brackets"`
-		Statuses      []string `url:"statuses,omitempty,brackets"`
-		Priorities    []int    `url:"priorities,omitempty,brackets"`
-		Aubtypes      []string `url:"subtypes,omitempty,brackets"`
-		ColumnPHIDs   []string `url:"columnPHIDs,omitempty,brackets"`
-		HasParents    bool     `url:"hasParents,omitempty"`
-		HasSubtasks   bool     `url:"hasSubtasks,omitempty"`
-		ParentIDs     []string `url:"parentIDs,omitempty,brackets"`
-		SubtaskIDs    []string `url:"subtaskIDs,omitempty,brackets"`
-		CreatedStart  int64    `url:"createdStart,omitempty"`
-		ModifiedStart int64    `url:"modifiedStart,omitempty"`
-		CreatedEnd    int64    `url:"createdEnd,omitempty"`
-		ModifiedEnd   int64    `url:"modifiedEnd,omitempty"`
-		ClosedStart   int64    `url:"closedStart,omitempty"`
-		ClosedEnd     int64    `url:"closedEnd,omitempty"`
-		CloserPHIDs   []string `url:"closerPHIDs,omitempty"`
-		Query         string   `url:"query,omitempty"`
-		Subscribers   []string `url:"subscribers,omitempty,brackets"`
-		Projects      []string `url:"projects,omitempty,brackets"`
-		Spaces        []string `url:"spaces,omitempty,brackets"`
+		Ids           []int            `url:"ids,omitempty,brackets"`
+		Phids         []string         `url:"phids,omitempty,brackets"`
+		Assigned      []string         `url:"assigned,omitempty,brackets"`
+		AuthorPHIDs   []string         `url:"authorPHIDs,omitempty,brackets"`
+		Statuses      []TicketStatus   `url:"statuses,omitempty,brackets"`
+		Priorities    []TicketPriority `url:"priorities,omitempty,brackets"`
+		Aubtypes      []string         `url:"subtypes,omitempty,brackets"`
+		ColumnPHIDs   []string         `url:"columnPHIDs,omitempty,brackets"`
+		HasParents    bool             `url:"hasParents,omitempty"`
+		HasSubtasks   bool             `url:"hasSubtasks,omitempty"`
+		ParentIDs     []string         `url:"parentIDs,omitempty,brackets"`
+		SubtaskIDs    []string         `url:"subtaskIDs,omitempty,brackets"`
+		CreatedStart  int64            `url:"createdStart,omitempty"`
+		ModifiedStart int64            `url:"modifiedStart,omitempty"`
+		CreatedEnd    int64            `url:"createdEnd,omitempty"`
+		ModifiedEnd   int64            `url:"modifiedEnd,omitempty"`
+		ClosedStart   int64            `url:"closedStart,omitempty"`
+		ClosedEnd     int64            `url:"closedEnd,omitempty"`
+		CloserPHIDs   []string         `url:"closerPHIDs,omitempty"`
+		Query         string           `url:"query,omitempty"`
+		Subscribers   []string         `url:"subscribers,omitempty,brackets"`
+		Projects      []string         `url:"projects,omitempty,brackets"`
+		Spaces        []string         `url:"spaces,omitempty,brackets"`
 	} `url:"constraints,omitempty"`
 	Order string `url:"order,omitempty"`
 }
@@ -59,15 +59,15 @@ type Ticket struct {
 		AuthorPHID string `json:"authorPHID"`
 		OwnerPHID  string `json:"ownerPHID"`
 		Status     struct {
-			Value string `json:"value"`
-			Name  string `json:"name"`
-			Color string `json:"color"`
+			Value TicketStatus `json:"value"`
+			Name  string       `json:"name"`
+			Color string       `json:"color"`
 		} `json:"status"`
 		Priority struct {
-			Value       int     `json:"value"`
-			Subpriority float64 `json:"subpriority"`
-			Name        string  `json:"name"`
-			Color       string  `json:"color"`
+			Value       TicketPriority `json:"value"`
+			Subpriority float64        `json:"subpriority"`
+			Name        string         `json:"name"`
+			Color       string         `json:"color"`
 		} `json:"priority"`
 		Points       string `json:"points"`
 		Subtype      string `json:"subtype"`
@@ -0,0 +1,192 @@
+package phabricator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// TransactionSearchArgs are the arguments of transaction.search.
+// Either ObjectIdentifier or Constraints.Phids is required.
+type TransactionSearchArgs struct {
+	// PHID or monogram (T123, D45) of the object whose history to search
+	ObjectIdentifier string `url:"objectIdentifier,omitempty"`
+	Constraints      struct {
+		Phids       []string `url:"phids,omitempty,brackets"`
+		AuthorPHIDs []string `url:"authorPHIDs,omitempty,brackets"`
+	} `url:"constraints,omitempty"`
+	// transaction.search can't filter by type, so results of other
+	// types are dropped client-side, see Phabricator.Transactions
+	Types []TransactionType `url:"-"`
+}
+
+// TransactionType is the kind of change a transaction made, e.g. status.
+// Transactions that Conduit doesn't describe have an empty type.
+type TransactionType string
+
+// Transaction types of tasks and revisions
+const (
+	TransactionTypeCreate      TransactionType = "create"
+	TransactionTypeComment     TransactionType = "comment"
+	TransactionTypeTitle       TransactionType = "title"
+	TransactionTypeProjects    TransactionType = "projects"
+	TransactionTypeSubscribers TransactionType = "subscribers"
+
+	TransactionTypeDescription TransactionType = "description"
+	TransactionTypeStatus      TransactionType = "status"
+	TransactionTypePriority    TransactionType = "priority"
+	TransactionTypeOwner       TransactionType = "owner"
+	TransactionTypePoints      TransactionType = "points"
+	TransactionTypeColumn      TransactionType = "column"
+	TransactionTypeMergedInto  TransactionType = "mergedinto"
+
+	TransactionTypeSummary       TransactionType = "summary"
+	TransactionTypeTestPlan      TransactionType = "testPlan"
+	TransactionTypeUpdate        TransactionType = "update"
+	TransactionTypeReviewers     TransactionType = "reviewers"
+	TransactionTypeInline        TransactionType = "inline"
+	TransactionTypeAccept        TransactionType = "accept"
+	TransactionTypeReject        TransactionType = "reject"
+	TransactionTypeRequestReview TransactionType = "request-review"
+	TransactionTypePlanChanges   TransactionType = "plan-changes"
+	TransactionTypeAbandon       TransactionType = "abandon"
+	TransactionTypeClose         TransactionType = "close"
+)
+
+// TransactionComment is a single version of a transaction comment.
+// Every edit of a comment adds a version.
+type TransactionComment struct {
+	Id           int    `json:"id"`
+	Phid         string `json:"phid"`
+	Version      int    `json:"version"`
+	AuthorPHID   string `json:"authorPHID"`
+	DateCreated  int64  `json:"dateCreated"`
+	DateModified int64  `json:"dateModified"`
+	Removed      bool   `json:"removed"`
+	Content      struct {
+		Raw string `json:"raw"`
+	} `json:"content"`
+}
+
+// TransactionOperation is an edge change, e.g. a project added to a task
+type TransactionOperation struct {
+	Operation string `json:"operation"`
+	Phid      string `json:"phid"`
+}
+
+// TransactionFields describe the change made by a transaction. Most
+// types set Old and New, edge types such as projects set Operations.
+type TransactionFields struct {
+	Old        json.RawMessage        `json:"old,omitempty"`
+	New        json.RawMessage        `json:"new,omitempty"`
+	Operations []TransactionOperation `json:"operations,omitempty"`
+	// All fields as returned, including those specific to a single
+	// type, e.g. columnPHID of column moves
+	Raw map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the fields, which PHP serializes as an empty
+// list when there are none
+func (f *TransactionFields) UnmarshalJSON(data []byte) error {
+	*f = TransactionFields{}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("[]")) {
+		return nil
+	}
+	if err := json.Unmarshal(data, &f.Raw); err != nil {
+		return err
+	}
+	type fields TransactionFields
+	return json.Unmarshal(data, (*fields)(f))
+}
+
+// MarshalJSON encodes the fields as they were returned
+func (f TransactionFields) MarshalJSON() ([]byte, error) {
+	all := make(map[string]interface{}, len(f.Raw)+3)
+	for key, value := range f.Raw {
+		all[key] = value
+	}
+	if f.Old != nil {
+		all["old"] = f.Old
+	}
+	if f.New != nil {
+		all["new"] = f.New
+	}
+	if f.Operations != nil {
+		all["operations"] = f.Operations
+	}
+	return json.Marshal(all)
+}
+
+// Values decodes the old and new values into OLD and NEW
+func (f TransactionFields) Values(old, new interface{}) error {
+	if len(f.Old) > 0 {
+		if err := json.Unmarshal(f.Old, old); err != nil {
+			return err
+		}
+	}
+	if len(f.New) > 0 {
+		return json.Unmarshal(f.New, new)
+	}
+	return nil
+}
+
+// OldText returns the old value as text, empty if there's none
+func (f TransactionFields) OldText() string {
+	return rawText(f.Old)
+}
+
+// NewText returns the new value as text, empty if there's none
+func (f TransactionFields) NewText() string {
+	return rawText(f.New)
+}
+
+// rawText returns JSON strings unquoted and other values as JSON
+func rawText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// Transaction is a single change in the history of an object
+type Transaction struct {
+	Id           int                  `json:"id"`
+	Phid         string               `json:"phid"`
+	Type         TransactionType      `json:"type"`
+	AuthorPHID   string               `json:"authorPHID"`
+	ObjectPHID   string               `json:"objectPHID"`
+	DateCreated  int64                `json:"dateCreated"`
+	DateModified int64                `json:"dateModified"`
+	GroupID      string               `json:"groupID"`
+	Comments     []TransactionComment `json:"comments"`
+	Fields       TransactionFields    `json:"fields"`
+}
+
+// Comment returns the text of the latest version of the comment,
+// empty if the transaction has none or it was removed
+func (t *Transaction) Comment() string {
+	var latest *TransactionComment
+	for i := range t.Comments {
+		if latest == nil || t.Comments[i].Version > latest.Version {
+			latest = &t.Comments[i]
+		}
+	}
+	if latest == nil || latest.Removed {
+		return ""
+	}
+	return latest.Content.Raw
+}
+
+func (t *Transaction) String() string {
+	if comment := t.Comment(); comment != "" {
+		return fmt.Sprintf("[%s|%d]: %s", t.Type, t.Id, comment)
+	}
+	if t.Fields.Old != nil || t.Fields.New != nil {
+		return fmt.Sprintf("[%s|%d]: %s -> %s", t.Type, t.Id, t.Fields.OldText(), t.Fields.NewText())
+	}
+	return fmt.Sprintf("[%s|%d]", t.Type, t.Id)
+}
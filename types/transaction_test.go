@@ -0,0 +1,61 @@
+package phabricator
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const transactionJSON = `[
+	{"id": 1, "phid": "PHID-XACT-TASK-1", "type": "status", "objectPHID": "PHID-TASK-1",
+	 "comments": [], "fields": {"old": "open", "new": "resolved"}},
+	{"id": 2, "phid": "PHID-XACT-TASK-2", "type": "comment", "objectPHID": "PHID-TASK-1",
+	 "comments": [
+		{"id": 7, "version": 2, "content": {"raw": "Fixed, really"}},
+		{"id": 6, "version": 1, "content": {"raw": "Fixed"}}
+	 ], "fields": []},
+	{"id": 3, "phid": "PHID-XACT-TASK-3", "type": "projects", "objectPHID": "PHID-TASK-1",
+	 "comments": [], "fields": {"operations": [{"operation": "add", "phid": "PHID-PROJ-1"}]}},
+	{"id": 4, "phid": "PHID-XACT-TASK-4", "type": "priority", "objectPHID": "PHID-TASK-1",
+	 "comments": [], "fields": {"old": {"value": 50, "name": "Normal"}, "new": {"value": 80, "name": "High"}}},
+	{"id": 5, "phid": "PHID-XACT-TASK-5", "type": null, "objectPHID": "PHID-TASK-1",
+	 "comments": [{"id": 8, "version": 1, "removed": true, "content": {"raw": "Spam"}}], "fields": []}
+]`
+
+func TestTransaction(t *testing.T) {
+	var transactions []Transaction
+	if err := json.Unmarshal([]byte(transactionJSON), &transactions); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"[status|1]: open -> resolved",
+		"[comment|2]: Fixed, really",
+		"[projects|3]",
+		`[priority|4]: {"value": 50, "name": "Normal"} -> {"value": 80, "name": "High"}`,
+		"[|5]",
+	}
+	for i, transaction := range transactions {
+		if got := transaction.String(); got != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], got)
+		}
+	}
+	if ops := transactions[2].Fields.Operations; len(ops) != 1 || ops[0].Operation != "add" || ops[0].Phid != "PHID-PROJ-1" {
+		t.Errorf("Unexpected operations %+v", ops)
+	}
+
+	var old, new TicketPriorityInfo
+	if err := transactions[3].Fields.Values(&old, &new); err != nil {
+		t.Fatal(err)
+	}
+	if old.Value != TicketPriorityNormal || new.Name != "High" {
+		t.Errorf("Unexpected priority change %+v -> %+v", old, new)
+	}
+
+	// Fields round-trip, including those without a struct field
+	var fields TransactionFields
+	json.Unmarshal([]byte(`{"columnPHID": "PHID-PCOL-1", "old": null}`), &fields)
+	encoded, err := json.Marshal(fields)
+	if err != nil || !strings.Contains(string(encoded), `"columnPHID":"PHID-PCOL-1"`) {
+		t.Errorf("Unexpected encoded fields %s (%v)", encoded, err)
+	}
+}
@@ -0,0 +1,120 @@
+// Package user builds arguments for user.search
+//
+//	args := user.Query().Usernames("alice", "bob").WithAvailability().Args()
+//	results := phab.CallSearch(ctx, user.Endpoint, args, phabTypes.User{})
+package user
+
+import (
+	"time"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// Endpoint the built arguments are meant for
+const Endpoint = "user.search"
+
+// Builder builds UserSearchArgs. Every method modifies the builder
+// and returns it, so calls can be chained.
+type Builder struct {
+	args phabTypes.UserSearchArgs
+}
+
+// Query starts a new, unconstrained user query
+func Query() *Builder {
+	return &Builder{}
+}
+
+// Args returns the built arguments
+func (b *Builder) Args() phabTypes.UserSearchArgs {
+	return b.args
+}
+
+// QueryKey starts from a saved or builtin query, e.g. active or admin
+func (b *Builder) QueryKey(key string) *Builder {
+	b.args.QueryKey = key
+	return b
+}
+
+// IDs limits the results to users with IDS
+func (b *Builder) IDs(ids ...int) *Builder {
+	b.args.Constraints.Ids = append(b.args.Constraints.Ids, ids...)
+	return b
+}
+
+// PHIDs limits the results to users with PHIDS
+func (b *Builder) PHIDs(phids ...string) *Builder {
+	b.args.Constraints.Phids = append(b.args.Constraints.Phids, phids...)
+	return b
+}
+
+// Usernames limits the results to users with any of USERNAMES
+func (b *Builder) Usernames(usernames ...string) *Builder {
+	b.args.Constraints.Usernames = append(b.args.Constraints.Usernames, usernames...)
+	return b
+}
+
+// NameLike limits the results to users whose username or real name contains NAME
+func (b *Builder) NameLike(name string) *Builder {
+	b.args.Constraints.NameLike = name
+	return b
+}
+
+// Admins limits the results to administrators
+func (b *Builder) Admins() *Builder {
+	b.args.Constraints.IsAdmin = true
+	return b
+}
+
+// Disabled limits the results to disabled users
+func (b *Builder) Disabled() *Builder {
+	b.args.Constraints.IsDisabled = true
+	return b
+}
+
+// Bots limits the results to bots
+func (b *Builder) Bots() *Builder {
+	b.args.Constraints.IsBot = true
+	return b
+}
+
+// MailingLists limits the results to mailing lists
+func (b *Builder) MailingLists() *Builder {
+	b.args.Constraints.IsMailingList = true
+	return b
+}
+
+// NeedingApproval limits the results to users waiting for approval
+func (b *Builder) NeedingApproval() *Builder {
+	b.args.Constraints.NeedsApproval = true
+	return b
+}
+
+// CreatedAfter limits the results to users created at or after T
+func (b *Builder) CreatedAfter(t time.Time) *Builder {
+	b.args.Constraints.CreatedStart = t.Unix()
+	return b
+}
+
+// ModifiedAfter limits the results to users modified at or after T
+func (b *Builder) ModifiedAfter(t time.Time) *Builder {
+	b.args.Constraints.ModifiedStart = t.Unix()
+	return b
+}
+
+// Matching limits the results to users matching the full-text query TEXT
+func (b *Builder) Matching(text string) *Builder {
+	b.args.Constraints.Query = text
+	return b
+}
+
+// Order sets the result order, e.g. username
+func (b *Builder) Order(order string) *Builder {
+	b.args.Order = order
+	return b
+}
+
+// WithAvailability attaches the calendar availability of the users
+func (b *Builder) WithAvailability() *Builder {
+	b.args.Attachments.Availability = true
+	return b
+}
@@ -0,0 +1,41 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	query "github.com/google/go-querystring/query"
+)
+
+func TestQuery(t *testing.T) {
+	args := Query().
+		Usernames("alice", "bob").
+		Bots().
+		CreatedAfter(time.Unix(1600000000, 0)).
+		Matching("Alice").
+		Order("username").
+		WithAvailability().
+		Args()
+
+	values, err := query.Values(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"constraints[isBot]":        "true",
+		"constraints[createdStart]": "1600000000",
+		"constraints[query]":        "Alice",
+		"order":                     "username",
+		"attachments[availability]": "true",
+	} {
+		if value := values.Get(key); value != expected {
+			t.Errorf("%s: expected %q, got %q", key, expected, value)
+		}
+	}
+	if usernames := values["constraints[usernames][]"]; len(usernames) != 2 || usernames[0] != "alice" {
+		t.Errorf("Expected usernames alice and bob, got %v", usernames)
+	}
+	if _, set := values["constraints[isAdmin]"]; set {
+		t.Error("Admins constrained without being asked for")
+	}
+}
@@ -0,0 +1,230 @@
+package phabricator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	// https://github.com/Sirupsen/logrus
+	log "github.com/sirupsen/logrus"
+
+	// https://godoc.org/github.com/google/go-querystring/query
+	query "github.com/google/go-querystring/query"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+// ValidationError is returned when the arguments of a call don't match
+// the parameters conduit.query advertised for the endpoint.
+// Problems holds one human-readable line per offending parameter.
+type ValidationError struct {
+	Endpoint string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Invalid arguments for %s: %s", e.Endpoint, strings.Join(e.Problems, "; "))
+}
+
+// paramName strips the bracketed part of a form key, so that
+// "constraints[ids][0]" becomes "constraints"
+func paramName(key string) string {
+	if i := strings.Index(key, "["); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// paramSubkey returns the first bracketed part of a form key, so that
+// "attachments[subscribers]" becomes "subscribers"
+func paramSubkey(key string) string {
+	start := strings.Index(key, "[")
+	if start < 0 {
+		return ""
+	}
+	end := strings.Index(key[start:], "]")
+	if end < 0 {
+		return ""
+	}
+	return key[start+1 : start+end]
+}
+
+// searchArgTypes are the argument structs of the search endpoints.
+// Conduit describes constraints and attachments only as maps, so
+// their url tags are the reference for the accepted subkeys.
+var searchArgTypes = map[string]interface{}{
+	"maniphest.search":             phabTypes.TicketSearchArgs{},
+	"differential.revision.search": phabTypes.RevisionSearchArgs{},
+	"differential.diff.search":     phabTypes.DiffSearchArgs{},
+	"project.search":               phabTypes.ProjectSearchArgs{},
+	"user.search":                  phabTypes.UserSearchArgs{},
+	"diffusion.repository.search":  phabTypes.RepositorySearchArgs{},
+	"transaction.search":           phabTypes.TransactionSearchArgs{},
+}
+
+// urlName returns the form name go-querystring uses for FIELD
+func urlName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("url"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// knownSubkeys returns the subkeys of parameter NAME, e.g. constraints,
+// that the argument struct of ENDPOINT knows. The second return value
+// is false if there is nothing to check against.
+func knownSubkeys(endpoint, name string) (map[string]bool, bool) {
+	args, known := searchArgTypes[endpoint]
+	if !known {
+		return nil, false
+	}
+	typ := reflect.TypeOf(args)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if urlName(field) != name || field.Type.Kind() != reflect.Struct {
+			continue
+		}
+		subkeys := make(map[string]bool, field.Type.NumField())
+		for j := 0; j < field.Type.NumField(); j++ {
+			if subkey := urlName(field.Type.Field(j)); subkey != "-" {
+				subkeys[subkey] = true
+			}
+		}
+		return subkeys, true
+	}
+	return nil, false
+}
+
+// Conduit describes parameter types as e.g. "optional int (default = 100)"
+// or "list<map<string, wild>>". Anything not marked optional is required.
+func paramType(desc string) (typ string, optional bool) {
+	typ = strings.TrimSpace(desc)
+	if strings.HasPrefix(typ, "optional ") {
+		optional = true
+		typ = strings.TrimPrefix(typ, "optional ")
+	} else {
+		typ = strings.TrimPrefix(typ, "required ")
+	}
+	if i := strings.Index(typ, " ("); i >= 0 {
+		typ = typ[:i]
+	}
+	return typ, optional
+}
+
+// checkValue verifies a single form value against a Conduit type.
+// Only the scalar types we can reliably check are considered,
+// everything else (wild, phid, order, ...) is accepted as is.
+func checkValue(typ, value string) bool {
+	switch typ {
+	case "int", "list<int>":
+		_, err := strconv.Atoi(value)
+		return err == nil
+	case "bool", "map<string, bool>":
+		_, err := strconv.ParseBool(value)
+		return err == nil
+	}
+	return true
+}
+
+// validateValues checks form-encoded arguments against an endpoint's schema.
+// Conduit only describes top-level parameters, so nested keys such as
+// constraint names are checked against the type of their parent, and
+// against the argument struct of known search endpoints.
+func validateValues(endpoint string, einfo endpointInfo, values url.Values) error {
+	if len(einfo.Params) == 0 {
+		// Nothing to validate against
+		return nil
+	}
+	var problems []string
+	seen := make(map[string]bool)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := paramName(key)
+		seen[name] = true
+		desc, known := einfo.Params[name]
+		if !known {
+			problems = append(problems, fmt.Sprintf("unknown parameter %q", key))
+			continue
+		}
+		typ, _ := paramType(desc)
+		if key != name && !strings.HasPrefix(typ, "map<") && !strings.HasPrefix(typ, "list<") {
+			problems = append(problems, fmt.Sprintf("parameter %q of type %q can't have subkey %q", name, typ, paramSubkey(key)))
+			continue
+		}
+		if key != name {
+			if subkeys, ok := knownSubkeys(endpoint, name); ok && !subkeys[paramSubkey(key)] {
+				problems = append(problems, fmt.Sprintf("unknown %s key %q", name, paramSubkey(key)))
+				continue
+			}
+		}
+		for _, value := range values[key] {
+			if !checkValue(typ, value) {
+				problems = append(problems, fmt.Sprintf("parameter %q expects %s, got %q", key, typ, value))
+			}
+		}
+	}
+
+	required := make([]string, 0)
+	for name, desc := range einfo.Params {
+		if _, optional := paramType(desc); !optional && !seen[name] {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	for _, name := range required {
+		problems = append(problems, fmt.Sprintf("missing required parameter %q", name))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	logger.WithFields(log.Fields{
+		"endpoint": endpoint,
+		"problems": problems,
+	}).Error("Arguments don't match the endpoint schema")
+	return &ValidationError{Endpoint: endpoint, Problems: problems}
+}
+
+// Validate checks ARGUMENTS against the parameters conduit.query
+// reported for ENDPOINT without sending the call itself.
+// ARGUMENTS is either a search argument struct or *EditArguments.
+// Task statuses and priorities are checked against the instance too.
+// A *ValidationError is returned if any problems are found.
+func (p *Phabricator) Validate(ctx context.Context, endpoint string, arguments EndpointArguments) error {
+	if err := p.ensureEndpoints(ctx); err != nil {
+		return err
+	}
+	einfo, known := p.apiInfo[endpoint]
+	if !known {
+		return fmt.Errorf("Unknown endpoint %s", endpoint)
+	}
+	var values url.Values
+	if edit, ok := arguments.(*EditArguments); ok {
+		values = editArgsToValues(edit)
+	} else {
+		var err error
+		if values, err = query.Values(arguments); err != nil {
+			return err
+		}
+	}
+	return p.validateCall(ctx, endpoint, einfo, values)
+}
+
+// validateCall checks VALUES against the endpoint schema, then the
+// statuses and priorities they contain
+func (p *Phabricator) validateCall(ctx context.Context, endpoint string, einfo endpointInfo, values url.Values) error {
+	if err := validateValues(endpoint, einfo, values); err != nil {
+		return err
+	}
+	return p.validateEnums(ctx, endpoint, values)
+}
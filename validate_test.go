@@ -0,0 +1,120 @@
+package phabricator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	phabTypes "go.showmax.cc/phabricator/types"
+)
+
+var maniphestSearchInfo = endpointInfo{
+	Params: map[string]string{
+		"queryKey":    "optional string",
+		"constraints": "optional map<string, wild>",
+		"attachments": "optional map<string, bool>",
+		"order":       "optional order",
+		"before":      "optional string",
+		"after":       "optional string",
+		"limit":       "optional int (default = 100)",
+	},
+}
+
+var maniphestEditInfo = endpointInfo{
+	Params: map[string]string{
+		"transactions":     "list<map<string, wild>>",
+		"objectIdentifier": "optional id|phid|string",
+	},
+}
+
+func TestValidateSearchArguments(t *testing.T) {
+	phab := Phabricator{apiInfo: map[string]endpointInfo{"maniphest.search": maniphestSearchInfo}}
+	args := phabTypes.TicketSearchArgs{QueryKey: "open"}
+	args.Constraints.Ids = []int{1, 2}
+	args.Attachments.Projects = true
+	if err := phab.Validate(context.Background(), "maniphest.search", args); err != nil {
+		t.Errorf("Valid arguments rejected: %s", err)
+	}
+}
+
+func TestValidateUnknownParameter(t *testing.T) {
+	phab := Phabricator{apiInfo: map[string]endpointInfo{"user.search": {
+		Params: map[string]string{"queryKey": "optional string"},
+	}}}
+	args := phabTypes.UserSearchArgs{}
+	args.Constraints.Usernames = []string{"alice"}
+	err := phab.Validate(context.Background(), "user.search", args)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+	if len(verr.Problems) != 1 {
+		t.Errorf("Expected exactly one problem, got %v", verr.Problems)
+	}
+}
+
+func TestValidateMissingRequired(t *testing.T) {
+	phab := Phabricator{apiInfo: map[string]endpointInfo{"maniphest.edit": maniphestEditInfo}}
+	if err := phab.Validate(context.Background(), "maniphest.edit", &EditArguments{ObjectIdentifier: 1}); err == nil {
+		t.Error("Missing transactions not reported")
+	}
+	args := &EditArguments{
+		ObjectIdentifier: "T1",
+		Transactions:     []PhabTransaction{NewTransaction("title", "New title")},
+	}
+	if err := phab.Validate(context.Background(), "maniphest.edit", args); err != nil {
+		t.Errorf("Valid edit rejected: %s", err)
+	}
+}
+
+func TestValidateValueTypes(t *testing.T) {
+	values := map[string][]string{
+		"limit":                    {"many"},
+		"attachments[subscribers]": {"yes please"},
+		"queryKey[nested]":         {"x"},
+	}
+	err := validateValues("maniphest.search", maniphestSearchInfo, values)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+	if len(verr.Problems) != 3 {
+		t.Errorf("Expected three problems, got %v", verr.Problems)
+	}
+}
+
+func TestValidateConstraintKeys(t *testing.T) {
+	values := map[string][]string{
+		"constraints[ids][0]":      {"1"},
+		"constraints[statuses][0]": {"open"},
+		"constraints[owners][0]":   {"PHID-USER-1"},
+	}
+	err := validateValues("maniphest.search", maniphestSearchInfo, values)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+	if len(verr.Problems) != 1 || !strings.Contains(verr.Problems[0], `"owners"`) {
+		t.Errorf("Expected only the owners constraint to be reported, got %v", verr.Problems)
+	}
+}
+
+func TestValidateAttachmentKeys(t *testing.T) {
+	values := map[string][]string{
+		"attachments[projects]":  {"1"},
+		"attachments[reviewers]": {"1"},
+	}
+	err := validateValues("maniphest.search", maniphestSearchInfo, values)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+	if len(verr.Problems) != 1 || !strings.Contains(verr.Problems[0], `"reviewers"`) {
+		t.Errorf("Expected only the reviewers attachment to be reported, got %v", verr.Problems)
+	}
+
+	values = map[string][]string{"attachments[reviewers]": {"1"}}
+	if err := validateValues("differential.revision.search", maniphestSearchInfo, values); err != nil {
+		t.Errorf("Valid attachment rejected: %s", err)
+	}
+}
@@ -2,12 +2,6 @@ package phabricator
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/url"
-
-	// https://github.com/Sirupsen/logrus
-	log "github.com/sirupsen/logrus"
 )
 
 type WhoAmI struct {
@@ -27,28 +21,29 @@ type WhoamiResponse struct {
 	ErrorInfo string `json:"error_info"`
 }
 
-func (p *Phabricator) WhoAmI(ctx context.Context) *WhoAmI {
-	endpoint := "user.whoami"
-	data := fmt.Sprintf("api.token=%s", p.apiToken)
-	path, _ := url.Parse(endpoint)
-	fullEndpoint := p.apiEndpoint.ResolveReference(path).String()
+// WhoAmI calls user.whoami to find out who the token belongs to.
+// Errors reported by Phabricator are returned as *ConduitError.
+func (p *Phabricator) WhoAmI(ctx context.Context) (*WhoAmI, error) {
+	var who WhoAmI
+	if err := p.call(ctx, "user.whoami", "", &who); err != nil {
+		return nil, err
+	}
+	return &who, nil
+}
 
-	body, err := p.postRequest(ctx, fullEndpoint, data)
-	if err != nil {
-		logger.WithFields(log.Fields{
-			"error":    err,
-			"endpoint": endpoint,
-		}).Error("Request to Phabricator failed")
-		return nil
+// Viewer is like WhoAmI, but only asks Phabricator once and then
+// returns the cached user. Bots can use it to cheaply recognize
+// their own comments, edits etc.
+func (p *Phabricator) Viewer(ctx context.Context) (*WhoAmI, error) {
+	p.viewerLock.Lock()
+	defer p.viewerLock.Unlock()
+	if p.viewer != nil {
+		return p.viewer, nil
 	}
-	var who WhoamiResponse
-	fmt.Println(string(body))
-	err = json.Unmarshal(body, &who)
+	who, err := p.WhoAmI(ctx)
 	if err != nil {
-		logger.WithFields(log.Fields{
-			"error": err,
-		}).Error("Failed to decode JSON")
-		return nil
+		return nil, err
 	}
-	return &who.User
+	p.viewer = who
+	return who, nil
 }
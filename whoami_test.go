@@ -0,0 +1,99 @@
+package phabricator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWhoAmI(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		r.ParseForm()
+		if r.Form.Get("api.token") != "api-valid" {
+			fmt.Fprint(w, `{"result": null, "error_code": "ERR-INVALID-AUTH", "error_info": "API token is invalid."}`)
+			return
+		}
+		fmt.Fprint(w, `{"result": {"phid": "PHID-USER-bot", "userName": "bot", "roles": ["bot", "verified", "approved", "activated"]}}`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	phab, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-valid"), WithLogLevel("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	who, err := phab.WhoAmI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if who.PHID != "PHID-USER-bot" || who.Username != "bot" {
+		t.Errorf("Unexpected user %+v", who)
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	for i := 0; i < 3; i++ {
+		if viewer, err := phab.Viewer(ctx); err != nil || viewer.PHID != "PHID-USER-bot" {
+			t.Errorf("Unexpected viewer %+v (%v)", viewer, err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Viewer sent %d requests, expected 1", n)
+	}
+
+	phab, err = New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-revoked"), WithLogLevel("fatal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = phab.WhoAmI(ctx)
+	if cerr, ok := err.(*ConduitError); !ok || cerr.Code != "ERR-INVALID-AUTH" {
+		t.Errorf("Expected ERR-INVALID-AUTH, got %v", err)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.Form.Get("api.token") {
+		case "api-active":
+			fmt.Fprint(w, `{"result": {"userName": "bot", "roles": ["bot", "verified", "approved", "activated"]}}`)
+		case "api-disabled":
+			fmt.Fprint(w, `{"result": {"userName": "gone", "roles": ["disabled", "verified", "approved"]}}`)
+		case "api-unapproved":
+			fmt.Fprint(w, `{"result": {"userName": "new", "roles": ["verified"]}}`)
+		default:
+			fmt.Fprint(w, `{"result": null, "error_code": "ERR-INVALID-AUTH", "error_info": "API token is invalid."}`)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	for token, reason := range map[string]string{
+		"api-disabled":   "account is disabled",
+		"api-unapproved": "account is not approved",
+		"api-revoked":    "API token is invalid.",
+	} {
+		_, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken(token), WithLogLevel("fatal"), WithPreflight())
+		authErr, ok := err.(*AuthError)
+		if !ok {
+			t.Errorf("%s: expected an *AuthError, got %v", token, err)
+			continue
+		}
+		if authErr.Reason != reason {
+			t.Errorf("%s: unexpected reason %q", token, authErr.Reason)
+		}
+	}
+
+	phab, err := New(ctx, WithAPI(server.URL+"/api/"), WithToken("api-active"), WithLogLevel("fatal"), WithPreflight())
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewer, err := phab.Viewer(ctx)
+	if err != nil || !viewer.IsBot() {
+		t.Errorf("Preflight viewer not cached as a bot: %+v (%v)", viewer, err)
+	}
+}